@@ -1,4 +1,4 @@
-package main
+package atom
 
 type AtomAstType int
 
@@ -32,6 +32,7 @@ const (
 	AstTypeAsyncFunctionExpression
 	AstTypeFunctionExpression
 	AstTypeCall
+	AstTypeImport
 	AstTypeIndex
 	AstTypeMember
 	AstTypeAllocation
@@ -91,6 +92,7 @@ const (
 	AstTypeConstStatement
 	AstTypeLocalStatement
 	AstTypeImportStatement
+	AstTypeExportStatement
 	AstTypeIfStatement
 	AstTypeSwitchStatement
 	AstTypeWhileStatement
@@ -100,6 +102,98 @@ const (
 	AstInvalid
 )
 
+// astTypeNames are the names of the node types, without the AstType prefix.
+var astTypeNames = map[AtomAstType]string{
+	AstTypeIdn:                     "Idn",
+	AstTypeInt:                     "Int",
+	AstTypeNum:                     "Num",
+	AstTypeStr:                     "Str",
+	AstTypeBool:                    "Bool",
+	AstTypeNull:                    "Null",
+	AstTypeBase:                    "Base",
+	AstTypeArray:                   "Array",
+	AstTypeObject:                  "Object",
+	AstTypeKeyValue:                "KeyValue",
+	AstTypeAsyncFunctionExpression: "AsyncFunctionExpression",
+	AstTypeFunctionExpression:      "FunctionExpression",
+	AstTypeCall:                    "Call",
+	AstTypeImport:                  "Import",
+	AstTypeIndex:                   "Index",
+	AstTypeMember:                  "Member",
+	AstTypeAllocation:              "Allocation",
+	AstTypePostfixInc:              "PostfixInc",
+	AstTypePostfixDec:              "PostfixDec",
+	AstTypeUnaryBitNot:             "UnaryBitNot",
+	AstTypeUnaryNot:                "UnaryNot",
+	AstTypeUnaryNeg:                "UnaryNeg",
+	AstTypeUnaryPos:                "UnaryPos",
+	AstTypeUnaryInc:                "UnaryInc",
+	AstTypeUnaryDec:                "UnaryDec",
+	AstTypeUnaryTypeof:             "UnaryTypeof",
+	AstTypeUnaryAwait:              "UnaryAwait",
+	AstTypeBinaryMul:               "BinaryMul",
+	AstTypeBinaryDiv:               "BinaryDiv",
+	AstTypeBinaryMod:               "BinaryMod",
+	AstTypeBinaryAdd:               "BinaryAdd",
+	AstTypeBinarySub:               "BinarySub",
+	AstTypeBinaryShiftRight:        "BinaryShiftRight",
+	AstTypeBinaryShiftLeft:         "BinaryShiftLeft",
+	AstTypeBinaryGreaterThan:       "BinaryGreaterThan",
+	AstTypeBinaryGreaterThanEqual:  "BinaryGreaterThanEqual",
+	AstTypeBinaryLessThan:          "BinaryLessThan",
+	AstTypeBinaryLessThanEqual:     "BinaryLessThanEqual",
+	AstTypeBinaryEqual:             "BinaryEqual",
+	AstTypeBinaryNotEqual:          "BinaryNotEqual",
+	AstTypeBinaryAnd:               "BinaryAnd",
+	AstTypeBinaryOr:                "BinaryOr",
+	AstTypeBinaryXor:               "BinaryXor",
+	AstTypeLogicalAnd:              "LogicalAnd",
+	AstTypeLogicalOr:               "LogicalOr",
+	AstTypeAssign:                  "Assign",
+	AstTypeMulAssign:               "MulAssign",
+	AstTypeDivAssign:               "DivAssign",
+	AstTypeModAssign:               "ModAssign",
+	AstTypeAddAssign:               "AddAssign",
+	AstTypeSubAssign:               "SubAssign",
+	AstTypeLeftShiftAssign:         "LeftShiftAssign",
+	AstTypeRightShiftAssign:        "RightShiftAssign",
+	AstTypeBitwiseAndAssign:        "BitwiseAndAssign",
+	AstTypeBitwiseOrAssign:         "BitwiseOrAssign",
+	AstTypeBitwiseXorAssign:        "BitwiseXorAssign",
+	AstTypeIfExpression:            "IfExpression",
+	AstTypeSwitchExpression:        "SwitchExpression",
+	AstTypeCatchExpression:         "CatchExpression",
+	AstTypeBreakStatement:          "BreakStatement",
+	AstTypeContinueStatement:       "ContinueStatement",
+	AstTypeReturnStatement:         "ReturnStatement",
+	AstTypeEmptyStatement:          "EmptyStatement",
+	AstTypeExpressionStatement:     "ExpressionStatement",
+	AstTypeClass:                   "Class",
+	AstTypeEnum:                    "Enum",
+	AstTypeAsyncFunction:           "AsyncFunction",
+	AstTypeFunction:                "Function",
+	AstTypeBlock:                   "Block",
+	AstTypeVarStatement:            "VarStatement",
+	AstTypeConstStatement:          "ConstStatement",
+	AstTypeLocalStatement:          "LocalStatement",
+	AstTypeImportStatement:         "ImportStatement",
+	AstTypeExportStatement:         "ExportStatement",
+	AstTypeIfStatement:             "IfStatement",
+	AstTypeSwitchStatement:         "SwitchStatement",
+	AstTypeWhileStatement:          "WhileStatement",
+	AstTypeDoWhileStatement:        "DoWhileStatement",
+	AstTypeForStatement:            "ForStatement",
+	AstTypeProgram:                 "Program",
+	AstInvalid:                     "Invalid",
+}
+
+func (t AtomAstType) String() string {
+	if name, ok := astTypeNames[t]; ok {
+		return name
+	}
+	return "Invalid"
+}
+
 func NewAtomAst(astType AtomAstType, position AtomPosition) *AtomAst {
 	return &AtomAst{
 		AstType:  astType,
@@ -267,6 +361,12 @@ func NewCall(ast0 *AtomAst, args []*AtomAst, position AtomPosition) *AtomAst {
 	return ast
 }
 
+func NewImport(path *AtomAst, position AtomPosition) *AtomAst {
+	ast := NewAtomAst(AstTypeImport, position)
+	ast.Ast0 = path
+	return ast
+}
+
 func NewAllocation(ast0 *AtomAst, position AtomPosition) *AtomAst {
 	ast := NewAtomAst(AstTypeAllocation, position)
 	ast.Ast0 = ast0
@@ -317,10 +417,18 @@ func NewCatchExpression(condition *AtomAst, variable *AtomAst, body []*AtomAst,
 	return ast
 }
 
-func NewImportStatement(path *AtomAst, names []*AtomAst, position AtomPosition) *AtomAst {
+func NewImportStatement(path *AtomAst, alias *AtomAst, names []*AtomAst, aliases []*AtomAst, position AtomPosition) *AtomAst {
 	ast := NewAtomAst(AstTypeImportStatement, position)
 	ast.Ast0 = path
+	ast.Ast1 = alias
 	ast.Arr0 = names
+	ast.Arr1 = aliases
+	return ast
+}
+
+func NewExportStatement(declaration *AtomAst, position AtomPosition) *AtomAst {
+	ast := NewAtomAst(AstTypeExportStatement, position)
+	ast.Ast0 = declaration
 	return ast
 }
 
@@ -0,0 +1,188 @@
+package atom
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+)
+
+// astField names one child of a node in its JSON form, taken from
+// one of the Str0, Ast and Arr fields of AtomAst.
+type astField struct {
+	name  string
+	str   *string
+	node  *AtomAst
+	nodes []*AtomAst
+	list  bool // nodes is written as an array even when empty
+	cases bool // nodes are Array nodes written as arrays of patterns
+}
+
+func astNode(name string, node *AtomAst) astField {
+	return astField{name: name, node: node}
+}
+
+func astNodes(name string, nodes []*AtomAst) astField {
+	return astField{name: name, nodes: nodes, list: true}
+}
+
+// astFields maps the fields a node type uses to property names.
+func (a *AtomAst) astFields() []astField {
+	switch a.AstType {
+	case AstTypeIdn:
+		return []astField{{name: "name", str: &a.Str0}}
+	case AstTypeInt, AstTypeNum, AstTypeStr, AstTypeBool, AstTypeNull, AstTypeBase:
+		return []astField{{name: "value", str: &a.Str0}}
+	case AstTypeArray, AstTypeObject:
+		return []astField{astNodes("elements", a.Arr0)}
+	case AstTypeKeyValue:
+		return []astField{astNode("key", a.Ast0), astNode("value", a.Ast1)}
+	case AstTypeAsyncFunctionExpression, AstTypeFunctionExpression:
+		return []astField{astNodes("params", a.Arr0), astNodes("body", a.Arr1)}
+	case AstTypeCall:
+		return []astField{astNode("callee", a.Ast0), astNodes("arguments", a.Arr0)}
+	case AstTypeImport:
+		return []astField{astNode("path", a.Ast0)}
+	case AstTypeIndex:
+		return []astField{astNode("object", a.Ast0), astNode("index", a.Ast1)}
+	case AstTypeMember:
+		return []astField{astNode("object", a.Ast0), astNode("property", a.Ast1)}
+	case AstTypeAllocation:
+		return []astField{astNode("expression", a.Ast0)}
+	case AstTypePostfixInc, AstTypePostfixDec,
+		AstTypeUnaryBitNot, AstTypeUnaryNot, AstTypeUnaryNeg, AstTypeUnaryPos,
+		AstTypeUnaryInc, AstTypeUnaryDec, AstTypeUnaryTypeof, AstTypeUnaryAwait:
+		return []astField{astNode("operand", a.Ast0)}
+	case AstTypeIfExpression, AstTypeIfStatement:
+		return []astField{astNode("condition", a.Ast0), astNode("then", a.Ast1), astNode("else", a.Ast2)}
+	case AstTypeSwitchExpression, AstTypeSwitchStatement:
+		return []astField{
+			astNode("condition", a.Ast0),
+			{name: "cases", nodes: a.Arr0, list: true, cases: true},
+			astNodes("values", a.Arr1),
+			astNode("default", a.Ast1),
+		}
+	case AstTypeCatchExpression:
+		return []astField{astNode("expression", a.Ast0), astNode("variable", a.Ast1), astNodes("body", a.Arr0)}
+	case AstTypeReturnStatement:
+		return []astField{astNode("value", a.Ast0)}
+	case AstTypeExpressionStatement:
+		return []astField{astNode("expression", a.Ast0)}
+	case AstTypeClass:
+		return []astField{astNode("name", a.Ast0), astNode("base", a.Ast1), astNodes("body", a.Arr1)}
+	case AstTypeEnum:
+		return []astField{astNode("name", a.Ast0), astNodes("names", a.Arr0), astNodes("values", a.Arr1)}
+	case AstTypeAsyncFunction, AstTypeFunction:
+		return []astField{astNode("name", a.Ast0), astNodes("params", a.Arr0), astNodes("body", a.Arr1)}
+	case AstTypeBlock:
+		return []astField{astNodes("body", a.Arr0)}
+	case AstTypeVarStatement, AstTypeConstStatement, AstTypeLocalStatement:
+		return []astField{astNodes("names", a.Arr0), astNodes("values", a.Arr1)}
+	case AstTypeImportStatement:
+		return []astField{
+			astNode("path", a.Ast0),
+			astNode("alias", a.Ast1),
+			astNodes("names", a.Arr0),
+			astNodes("aliases", a.Arr1),
+		}
+	case AstTypeExportStatement:
+		return []astField{astNode("declaration", a.Ast0)}
+	case AstTypeWhileStatement, AstTypeDoWhileStatement:
+		return []astField{astNode("condition", a.Ast0), astNode("body", a.Ast1)}
+	case AstTypeForStatement:
+		return []astField{
+			astNode("initializer", a.Ast0),
+			astNode("condition", a.Ast1),
+			astNode("updater", a.Ast2),
+			astNode("body", a.Ast3),
+		}
+	case AstTypeProgram:
+		return []astField{astNodes("body", a.Arr1)}
+	}
+	// Binary operators and assignments
+	if a.AstType >= AstTypeBinaryMul && a.AstType <= AstTypeBitwiseXorAssign {
+		return []astField{astNode("left", a.Ast0), astNode("right", a.Ast1)}
+	}
+	return nil
+}
+
+// MarshalJSON writes the node as an object with its type name, the
+// properties of its type and its position. Missing children are null.
+func (a *AtomAst) MarshalJSON() ([]byte, error) {
+	buffer := bytes.Buffer{}
+	buffer.WriteString(`{"type":`)
+	typeName, _ := astJSON(a.AstType.String())
+	buffer.Write(typeName)
+
+	for _, field := range a.astFields() {
+		buffer.WriteString(",")
+		name, _ := astJSON(field.name)
+		buffer.Write(name)
+		buffer.WriteString(":")
+
+		var value any
+		switch {
+		case field.str != nil:
+			value = *field.str
+		case field.cases:
+			patterns := make([][]*AtomAst, len(field.nodes))
+			for index, node := range field.nodes {
+				patterns[index] = node.Arr0
+			}
+			value = patterns
+		case field.list && field.nodes == nil:
+			value = []*AtomAst{}
+		case field.list:
+			value = field.nodes
+		default:
+			value = field.node
+		}
+		encoded, err := astJSON(value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(encoded)
+	}
+
+	buffer.WriteString(`,"position":`)
+	position, err := astJSON(a.Position)
+	if err != nil {
+		return nil, err
+	}
+	buffer.Write(position)
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// astJSON encodes value without escaping the HTML characters
+// of operators and strings.
+func astJSON(value any) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// ParseString parses source without compiling it, the tree marshals
+// to the JSON printed by atom parse --json.
+func ParseString(file string, source string) (ast *AtomAst, err error) {
+	defer recoverCompileError(&err)
+
+	t := NewAtomTokenizer(file, source)
+	p := NewAtomParser(t)
+	return p.Parse(), nil
+}
+
+func ParseFile(file string) (*AtomAst, error) {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	content, err := readFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseString(absPath, content)
+}
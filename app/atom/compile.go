@@ -1,13 +1,11 @@
-package main
+package atom
 
 import (
 	"encoding/binary"
 	"fmt"
-	"math"
-	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
+	"slices"
+	"sort"
 	"strings"
 
 	runtime "dev.runtime"
@@ -16,7 +14,7 @@ import (
 type AtomPendingVariable struct {
 	ast      *AtomAst
 	atomFunc *runtime.AtomValue
-	index    int
+	store    bool // Assigned rather than read
 }
 
 /*
@@ -26,6 +24,12 @@ type AtomCompile struct {
 	state            *runtime.AtomState
 	parser           *AtomParser
 	pendingVariables []AtomPendingVariable
+	imported         map[string]bool
+	modules          map[string]string
+	exported         bool
+	exports          []string
+	predefined       []string
+	functions        []*runtime.AtomValue
 }
 
 func NewAtomCompile(parser *AtomParser, state *runtime.AtomState) *AtomCompile {
@@ -33,50 +37,15 @@ func NewAtomCompile(parser *AtomParser, state *runtime.AtomState) *AtomCompile {
 		parser:           parser,
 		state:            state,
 		pendingVariables: []AtomPendingVariable{},
+		imported:         map[string]bool{},
+		modules:          map[string]string{},
+		exported:         false,
+		exports:          []string{},
+		predefined:       []string{},
+		functions:        []*runtime.AtomValue{},
 	}
 }
 
-func isConstant(ast *AtomAst) bool {
-	switch ast.AstType {
-	case AstTypeNum,
-		AstTypeStr,
-		AstTypeBool,
-		AstTypeNull:
-		return true
-	case AstTypeInt:
-		return !strings.HasSuffix(ast.Str0, "n") && !strings.HasSuffix(ast.Str0, "N")
-	case AstTypeBinaryMul,
-		AstTypeBinaryDiv,
-		AstTypeBinaryMod,
-		AstTypeBinaryAdd,
-		AstTypeBinarySub,
-		AstTypeBinaryShiftRight,
-		AstTypeBinaryShiftLeft,
-		AstTypeBinaryGreaterThan,
-		AstTypeBinaryGreaterThanEqual,
-		AstTypeBinaryLessThan,
-		AstTypeBinaryLessThanEqual,
-		AstTypeBinaryEqual,
-		AstTypeBinaryNotEqual,
-		AstTypeBinaryAnd,
-		AstTypeBinaryOr,
-		AstTypeBinaryXor:
-		// Careful for recursive calls
-		return isConstant(ast.Ast0) && isConstant(ast.Ast1)
-	default:
-		return false
-	}
-}
-
-func hasDeclairation(block []*AtomAst) bool {
-	for _, stmt := range block {
-		if stmt.AstType == AstTypeVarStatement || stmt.AstType == AstTypeConstStatement || stmt.AstType == AstTypeLocalStatement {
-			return true
-		}
-	}
-	return false
-}
-
 func arrayReverse(path []string) []string {
 	reverse := []string{}
 	for i := len(path) - 1; i >= 0; i-- {
@@ -94,15 +63,40 @@ func currentLoop(scope *AtomScope) *AtomScope {
 	return nil
 }
 
-func currentBlock(scope *AtomScope) *AtomScope {
+func currentFunction(scope *AtomScope) *AtomScope {
 	for current := scope; current != nil; current = current.Parent {
-		if current.Type == AtomScopeTypeBlock {
+		if current.Type == AtomScopeTypeFunction || current.Type == AtomScopeTypeAsyncFunction {
 			return current
 		}
 	}
 	return nil
 }
 
+// enclosingFunction finds the function or global scope whose code
+// the statements of scope are compiled into.
+func enclosingFunction(scope *AtomScope) *AtomScope {
+	for current := scope; current != nil; current = current.Parent {
+		if current.Function != nil {
+			return current
+		}
+	}
+	return nil
+}
+
+// cellOpcode is the variant of a local instruction for captured locals.
+func cellOpcode(opcode runtime.OpCode) runtime.OpCode {
+	switch opcode {
+	case runtime.OpInitLocal:
+		return runtime.OpInitCell
+	case runtime.OpLoadLocal:
+		return runtime.OpLoadCell
+	case runtime.OpStoreLocal:
+		return runtime.OpStoreCell
+	default:
+		return opcode
+	}
+}
+
 func sendBreak(scope *AtomScope, jumpAddress int) {
 	loop := currentLoop(scope)
 	loop.Breaks = append(loop.Breaks, jumpAddress)
@@ -129,6 +123,8 @@ func (c *AtomCompile) emitByRuntimeValue(fn, obj *runtime.AtomValue) {
 		}
 	case runtime.AtomTypeNull:
 		c.emit(fn, runtime.OpLoadNull)
+	case runtime.AtomTypeBigInt:
+		c.emitConst(fn, runtime.OpLoadBigInt, obj)
 	default:
 		panic(fmt.Sprintf("invalid type: %d", obj.Type))
 	}
@@ -140,12 +136,17 @@ func (c *AtomCompile) emit(atomFunc *runtime.AtomValue, opcode runtime.OpCode) {
 }
 
 func (c *AtomCompile) emitInt(atomFunc *runtime.AtomValue, opcode runtime.OpCode, intValue int) {
+	c.emit(atomFunc, opcode)
+	c.emitOperand(atomFunc, intValue)
+}
+
+func (c *AtomCompile) emitOperand(atomFunc *runtime.AtomValue, intValue int) {
 	// Convert int32 to 4 bytes using little-endian encoding
 	bytes := []byte{0, 0, 0, 0}
 	binary.LittleEndian.PutUint32(bytes, uint32(intValue))
 
 	atomFunc.Obj.(*runtime.AtomCode).Code = append(
-		append(atomFunc.Obj.(*runtime.AtomCode).Code, opcode),
+		atomFunc.Obj.(*runtime.AtomCode).Code,
 		runtime.OpCode(bytes[0]),
 		runtime.OpCode(bytes[1]),
 		runtime.OpCode(bytes[2]),
@@ -153,53 +154,29 @@ func (c *AtomCompile) emitInt(atomFunc *runtime.AtomValue, opcode runtime.OpCode
 	)
 }
 
-func (c *AtomCompile) emitNum(atomFunc *runtime.AtomValue, opcode runtime.OpCode, numValue float64) {
-	bytes := []byte{0, 0, 0, 0, 0, 0, 0, 0}
-	binary.LittleEndian.PutUint64(bytes, uint64(math.Float64bits(numValue)))
+// emitConst emits an instruction whose operand is the index
+// of value in the constant pool of atomFunc.
+func (c *AtomCompile) emitConst(atomFunc *runtime.AtomValue, opcode runtime.OpCode, value *runtime.AtomValue) {
+	c.emitInt(atomFunc, opcode, atomFunc.Obj.(*runtime.AtomCode).AddConstant(value))
+}
 
-	atomFunc.Obj.(*runtime.AtomCode).Code = append(
-		append(atomFunc.Obj.(*runtime.AtomCode).Code, opcode),
-		runtime.OpCode(bytes[0]),
-		runtime.OpCode(bytes[1]),
-		runtime.OpCode(bytes[2]),
-		runtime.OpCode(bytes[3]),
-		runtime.OpCode(bytes[4]),
-		runtime.OpCode(bytes[5]),
-		runtime.OpCode(bytes[6]),
-		runtime.OpCode(bytes[7]),
-	)
+func (c *AtomCompile) emitNum(atomFunc *runtime.AtomValue, opcode runtime.OpCode, numValue float64) {
+	c.emitConst(atomFunc, opcode, runtime.NewAtomValueNum(numValue))
 }
 
 func (c *AtomCompile) emitStr(atomFunc *runtime.AtomValue, opcode runtime.OpCode, strValue string) {
-	bytes := []byte(strValue)
-
-	opcodes := make([]runtime.OpCode, len(bytes)+1)
-	for i, b := range bytes {
-		opcodes[i] = runtime.OpCode(b)
-	}
-	opcodes[len(bytes)] = '\x00' // Null byte
-
-	atomFunc.Obj.(*runtime.AtomCode).Code =
-		append(
-			append(atomFunc.Obj.(*runtime.AtomCode).Code, opcode),
-			opcodes...,
-		)
+	c.emitConst(atomFunc, opcode, c.state.Intern(strValue))
 }
 
 func (c *AtomCompile) emitWord(atomFunc *runtime.AtomValue, strValue string) {
-	bytes := []byte(strValue)
-
-	opcodes := make([]runtime.OpCode, len(bytes)+1)
-	for i, b := range bytes {
-		opcodes[i] = runtime.OpCode(b)
-	}
-	opcodes[len(bytes)] = '\x00' // Null byte
+	c.emitOperand(atomFunc, atomFunc.Obj.(*runtime.AtomCode).AddConstant(c.state.Intern(strValue)))
+}
 
-	atomFunc.Obj.(*runtime.AtomCode).Code =
-		append(
-			atomFunc.Obj.(*runtime.AtomCode).Code,
-			opcodes...,
-		)
+// emitAttribute emits an attribute instruction with the constant index
+// of name and a new inline cache of atomFunc.
+func (c *AtomCompile) emitAttribute(atomFunc *runtime.AtomValue, opcode runtime.OpCode, name string) {
+	c.emitStr(atomFunc, opcode, name)
+	c.emitOperand(atomFunc, atomFunc.Obj.(*runtime.AtomCode).AddCache())
 }
 
 func (c *AtomCompile) emitJump(atomFunc *runtime.AtomValue, opcode runtime.OpCode) int {
@@ -220,7 +197,7 @@ func (c *AtomCompile) emitLine(atomFunc *runtime.AtomValue, pos AtomPosition) {
 	})
 }
 
-func (c *AtomCompile) emitVar(atomFunc *runtime.AtomValue, scope *AtomScope, ast *AtomAst, global, constant bool) {
+func (c *AtomCompile) emitVar(atomFunc *runtime.AtomValue, scope *AtomScope, ast *AtomAst, global, constant bool) *AtomSymbol {
 	if _, exists := scope.Names[ast.Str0]; exists {
 		Error(
 			c.parser.tokenizer.file,
@@ -251,14 +228,103 @@ func (c *AtomCompile) emitVar(atomFunc *runtime.AtomValue, scope *AtomScope, ast
 	}
 
 	// Save to symbol table
-	scope.Names[name] = NewAtomSymbol(
+	symbol := NewAtomSymbol(
 		name,
 		global,
 		constant,
 	)
+	scope.Names[name] = symbol
 
 	c.emitLine(atomFunc, ast.Position)
-	c.emitStr(atomFunc, runtime.OpInitLocal, name)
+	if global {
+		c.emitStr(atomFunc, runtime.OpInitName, name)
+		return symbol
+	}
+
+	// Locals get the next slot of the function
+	code := atomFunc.Obj.(*runtime.AtomCode)
+	symbol.slot = code.Locals
+	symbol.owner = atomFunc
+	code.Locals++
+	code.Symbols = append(code.Symbols, name)
+	c.emitLocal(atomFunc, symbol, runtime.OpInitLocal)
+	return symbol
+}
+
+// emitLocal emits an instruction on a local of the function declaring
+// it, the cell variant once a closure captured the local.
+func (c *AtomCompile) emitLocal(atomFunc *runtime.AtomValue, symbol *AtomSymbol, opcode runtime.OpCode) {
+	if symbol.captured {
+		opcode = cellOpcode(opcode)
+	} else {
+		symbol.uses = append(symbol.uses, c.here(atomFunc))
+	}
+	c.emitInt(atomFunc, opcode, symbol.slot)
+}
+
+// captureLocal moves a local to a cell, the instructions
+// already emitted for it are patched.
+func (c *AtomCompile) captureLocal(symbol *AtomSymbol) {
+	if symbol.captured {
+		return
+	}
+	symbol.captured = true
+	code := symbol.owner.Obj.(*runtime.AtomCode)
+	for _, address := range symbol.uses {
+		code.Code[address] = cellOpcode(code.Code[address])
+	}
+	symbol.uses = nil
+}
+
+// capture returns the index of a captured local among the cells of the
+// function compiled in scope. Functions between the one declaring the
+// local and this one capture it too, to pass the cell along.
+func (c *AtomCompile) capture(scope *AtomScope, symbol *AtomSymbol) int {
+	function := enclosingFunction(scope)
+	if index, exists := function.Captures[symbol]; exists {
+		return index
+	}
+
+	outer := enclosingFunction(function.Parent)
+	capture := runtime.AtomCapture{Local: true, Index: symbol.slot}
+	if outer.Function != symbol.owner {
+		capture = runtime.AtomCapture{Local: false, Index: c.capture(outer, symbol)}
+	}
+
+	code := function.Function.Obj.(*runtime.AtomCode)
+	code.Captures = append(code.Captures, capture)
+	function.Captures[symbol] = len(code.Captures) - 1
+	return function.Captures[symbol]
+}
+
+func (c *AtomCompile) saveFunction(atomFunc *runtime.AtomValue) int {
+	c.functions = append(c.functions, atomFunc)
+	return c.state.SaveFunction(atomFunc)
+}
+
+// optimize runs the bytecode optimizer on the program and its functions,
+// after the whole program is compiled since a capture patches the code
+// of the function declaring the local.
+func (c *AtomCompile) optimize(programFunc *runtime.AtomValue) {
+	if !c.state.Optimize {
+		return
+	}
+	runtime.Optimize(programFunc.Obj.(*runtime.AtomCode))
+	for _, atomFunc := range c.functions {
+		runtime.Optimize(atomFunc.Obj.(*runtime.AtomCode))
+	}
+}
+
+// verify checks the program and its functions with the bytecode
+// verifier once they are optimized, rejected code is a compiler bug.
+func (c *AtomCompile) verify(programFunc *runtime.AtomValue) {
+	functions := c.state.FunctionTable.Len()
+	codes := []*runtime.AtomValue{programFunc}
+	for _, atomFunc := range append(codes, c.functions...) {
+		if err := runtime.Verify(atomFunc.Obj.(*runtime.AtomCode), functions); err != nil {
+			panic(&AtomCompileError{Message: fmt.Sprintf("Invalid bytecode: %s", err.Error())})
+		}
+	}
 }
 
 func (c *AtomCompile) here(atomFunc *runtime.AtomValue) int {
@@ -325,16 +391,16 @@ func (c *AtomCompile) identifier(fn *runtime.AtomValue, scope *AtomScope, ast *A
 
 	if !c.isDefined(scope, ast.Str0) {
 		// Resolve to global
-		c.emitStr(fn, runtime.OpLoadName, ast.Str0)
+		c.emitStr(fn, opcode, ast.Str0)
 		c.pendingVariables = append(c.pendingVariables, AtomPendingVariable{
 			ast:      ast,
 			atomFunc: fn,
-			index:    0,
+			store:    opcode == runtime.OpStoreName,
 		})
 		return
 	}
 	symbol := c.lookup(scope, ast.Str0)
-	if opcode == runtime.OpStoreLocal && symbol.constant {
+	if opcode == runtime.OpStoreName && symbol.constant {
 		Error(
 			c.parser.tokenizer.file,
 			c.parser.tokenizer.data,
@@ -342,10 +408,33 @@ func (c *AtomCompile) identifier(fn *runtime.AtomValue, scope *AtomScope, ast *A
 			ast.Position,
 		)
 	}
-	c.emitStr(fn, opcode, ast.Str0)
+	if symbol.global {
+		c.emitStr(fn, opcode, ast.Str0)
+		return
+	}
+
+	local, captured := runtime.OpLoadLocal, runtime.OpLoadCapture
+	if opcode == runtime.OpStoreName {
+		local, captured = runtime.OpStoreLocal, runtime.OpStoreCapture
+	}
+	if symbol.owner == fn {
+		c.emitLocal(fn, symbol, local)
+		return
+	}
+	// Declared by an enclosing function
+	c.captureLocal(symbol)
+	c.emitInt(fn, captured, c.capture(scope, symbol))
 }
 
 func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *AtomAst) {
+	if isFoldable(ast) {
+		if value := Eval(c, scope, ast); value != nil {
+			c.emitLine(fn, ast.Position)
+			c.emitByRuntimeValue(fn, value)
+			return
+		}
+	}
+
 	switch ast.AstType {
 	case AstTypeIdn:
 		{
@@ -353,109 +442,13 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 			c.identifier(fn, scope, ast, runtime.OpLoadName)
 		}
 
-	case AstTypeInt:
-		c.emitLine(fn, ast.Position)
-
-		if strings.HasSuffix(ast.Str0, "n") || strings.HasSuffix(ast.Str0, "N") {
-			ast.Str0 = strings.TrimSuffix(ast.Str0, "n")
-			ast.Str0 = strings.TrimSuffix(ast.Str0, "N")
-			c.emitStr(fn, runtime.OpLoadBigInt, ast.Str0)
-			return
-		}
-
-		intValue, err := strconv.Atoi(ast.Str0)
-		var overflowed bool
-		if after, ok := strings.CutPrefix(ast.Str0, "0x"); ok {
-			_intValue, _err := strconv.ParseInt(after, 16, 64)
-			if _intValue > math.MaxInt32 || _intValue < math.MinInt32 {
-				overflowed = true
-			} else {
-				intValue = int(_intValue)
-			}
-			err = _err
-		} else if after, ok := strings.CutPrefix(ast.Str0, "0o"); ok {
-			_intValue, _err := strconv.ParseInt(after, 8, 64)
-			if _intValue > math.MaxInt32 || _intValue < math.MinInt32 {
-				overflowed = true
-			} else {
-				intValue = int(_intValue)
-			}
-			err = _err
-		} else if after, ok := strings.CutPrefix(ast.Str0, "0b"); ok {
-			_intValue, _err := strconv.ParseInt(after, 2, 64)
-			if _intValue > math.MaxInt32 || _intValue < math.MinInt32 {
-				overflowed = true
-			} else {
-				intValue = int(_intValue)
-			}
-			err = _err
-		} else {
-			// Check for overflow in decimal case
-			_intValue, _err := strconv.ParseInt(ast.Str0, 10, 64)
-			if _err == nil && (_intValue > math.MaxInt32 || _intValue < math.MinInt32) {
-				overflowed = true
-			}
-		}
-
-		// If overflow detected, promote to float
-		if overflowed {
-			numValue, numErr := strconv.ParseFloat(ast.Str0, 64)
-			if numErr != nil {
-				Error(
-					c.parser.tokenizer.file,
-					c.parser.tokenizer.data,
-					"Invalid number",
-					ast.Position,
-				)
-			}
-			c.emitNum(fn, runtime.OpLoadNum, numValue)
-			return
-		}
-
-		if err != nil {
-			Error(
-				c.parser.tokenizer.file,
-				c.parser.tokenizer.data,
-				"Invalid integer",
-				ast.Position,
-			)
-		}
-		c.emitInt(
-			fn,
-			runtime.OpLoadInt,
-			intValue,
-		)
-
-	case AstTypeNum:
-		c.emitLine(fn, ast.Position)
-		numValue, err := strconv.ParseFloat(ast.Str0, 64)
-		if err != nil {
-			Error(
-				c.parser.tokenizer.file,
-				c.parser.tokenizer.data,
-				"Invalid number",
-				ast.Position,
-			)
-		}
-		c.emitNum(fn, runtime.OpLoadNum, numValue)
-
-	case AstTypeStr:
-		c.emitLine(fn, ast.Position)
-		c.emitStr(fn, runtime.OpLoadStr, ast.Str0)
-
-	case AstTypeBool:
-		c.emitLine(fn, ast.Position)
-		var boolValue byte
-		if ast.Str0 == "true" {
-			boolValue = 1
-		} else {
-			boolValue = 0
-		}
-		c.emitInt(fn, runtime.OpLoadBool, int(boolValue))
-
-	case AstTypeNull:
+	case AstTypeInt,
+		AstTypeNum,
+		AstTypeStr,
+		AstTypeBool,
+		AstTypeNull:
 		c.emitLine(fn, ast.Position)
-		c.emit(fn, runtime.OpLoadNull)
+		c.emitByRuntimeValue(fn, c.literal(ast))
 
 	case AstTypeBase:
 		// Guard
@@ -468,6 +461,9 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 			)
 		}
 
+		// The base class of self
+		c.emitLine(fn, ast.Position)
+		c.identifier(fn, scope, NewTerminal(AstTypeIdn, "self", ast.Position), runtime.OpLoadName)
 		c.emitLine(fn, ast.Position)
 		c.emit(fn, runtime.OpLoadBase)
 
@@ -515,15 +511,15 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 				scopeType = AtomScopeTypeAsyncFunction
 			}
 
-			funScope := NewAtomScope(scope, scopeType)
 			atomFunc := runtime.NewAtomGenericValue(
 				runtime.AtomTypeFunc,
 				runtime.NewAtomCode(c.parser.tokenizer.file, "anonymous", async, len(ast.Arr0)),
 			)
+			funScope := NewAtomFunctionScope(scope, scopeType, atomFunc)
 
 			params := ast.Arr0
 			//============================
-			fnOffset := c.state.SaveFunction(atomFunc)
+			fnOffset := c.saveFunction(atomFunc)
 
 			// Save to symbol table first to allow captures to reference it
 			c.emitLine(fn, ast.Position)
@@ -579,9 +575,7 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 			}
 			c.expression(scope, fn, obj)
 			c.emitLine(fn, ast.Position)
-			c.emitStr(fn, runtime.OpLoadStr, key.Str0)
-			c.emitLine(fn, ast.Position)
-			c.emit(fn, runtime.OpIndex)
+			c.emitAttribute(fn, runtime.OpLoadAttribute, key.Str0)
 		}
 
 	case AstTypeIndex:
@@ -601,9 +595,24 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 			for i := 0; i < len(args); i++ {
 				c.expression(scope, fn, args[i])
 			}
-			c.expression(scope, fn, funcAst)
+			// Method calls skip binding the method to the object
+			if funcAst.AstType == AstTypeMember && funcAst.Ast1.AstType == AstTypeIdn {
+				c.expression(scope, fn, funcAst.Ast0)
+				c.emitLine(fn, ast.Position)
+				c.emitAttribute(fn, runtime.OpCallAttribute, funcAst.Ast1.Str0)
+				c.emitOperand(fn, len(args))
+			} else {
+				c.expression(scope, fn, funcAst)
+				c.emitLine(fn, ast.Position)
+				c.emitInt(fn, runtime.OpCall, len(args))
+			}
+		}
+
+	case AstTypeImport:
+		{
+			c.expression(scope, fn, ast.Ast0)
 			c.emitLine(fn, ast.Position)
-			c.emitInt(fn, runtime.OpCall, len(args))
+			c.emitStr(fn, runtime.OpImportModule, c.parser.tokenizer.file)
 		}
 
 	case AstTypeAllocation:
@@ -711,7 +720,7 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 				return
 			}
 			callAst := ast.Ast0
-			if callAst.AstType != AstTypeCall {
+			if callAst.AstType != AstTypeCall && callAst.AstType != AstTypeImport {
 				Error(
 					c.parser.tokenizer.file,
 					c.parser.tokenizer.data,
@@ -727,12 +736,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryMul:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -743,12 +746,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryDiv:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -759,12 +756,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryMod:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -775,12 +766,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryAdd:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -791,12 +776,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinarySub:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -807,12 +786,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryShiftRight:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -823,12 +796,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryShiftLeft:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -839,12 +806,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryGreaterThan:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -855,12 +816,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryGreaterThanEqual:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -871,12 +826,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryLessThan:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -887,12 +836,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryLessThanEqual:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -903,12 +846,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryEqual:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -919,12 +856,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryNotEqual:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -935,12 +866,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryAnd:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -951,12 +876,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryOr:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -967,12 +886,6 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 
 	case AstTypeBinaryXor:
 		{
-			if isConstant(ast) {
-				result := Eval(c, ast)
-				c.emitLine(fn, ast.Position)
-				c.emitByRuntimeValue(fn, result)
-				return
-			}
 			lhs := ast.Ast0
 			rhs := ast.Ast1
 			c.expression(scope, fn, lhs)
@@ -1227,8 +1140,8 @@ func (c *AtomCompile) expression(scope *AtomScope, fn *runtime.AtomValue, ast *A
 				runtime.AtomTypeFunc,
 				runtime.NewAtomCode(c.parser.tokenizer.file, "catch", false, 1),
 			)
-			funScope := NewAtomScope(scope, AtomScopeTypeFunction)
-			fnOffset := c.state.SaveFunction(atomFunc)
+			funScope := NewAtomFunctionScope(scope, AtomScopeTypeFunction, atomFunc)
+			fnOffset := c.saveFunction(atomFunc)
 
 			c.expression(scope, fn, condition)
 			c.emitLine(fn, ast.Position)
@@ -1338,7 +1251,7 @@ func (c *AtomCompile) assignOp1(scope *AtomScope, fn *runtime.AtomValue, ast *At
 			}
 
 			c.emitLine(fn, ast.Position)
-			c.identifier(fn, scope, ast, runtime.OpStoreLocal)
+			c.identifier(fn, scope, ast, runtime.OpStoreName)
 		}
 	case AstTypeMember,
 		AstTypeIndex:
@@ -1389,16 +1302,14 @@ func (c *AtomCompile) assign(scope *AtomScope, fn *runtime.AtomValue, lhs *AtomA
 	case AstTypeIdn:
 		{
 			c.emitLine(fn, lhs.Position)
-			c.identifier(fn, scope, lhs, runtime.OpStoreLocal)
+			c.identifier(fn, scope, lhs, runtime.OpStoreName)
 		}
 
 	case AstTypeMember:
 		{
 			c.expression(scope, fn, lhs.Ast0)
 			c.emitLine(fn, lhs.Position)
-			c.emitStr(fn, runtime.OpLoadStr, lhs.Ast1.Str0)
-			c.emitLine(fn, lhs.Position)
-			c.emit(fn, runtime.OpSetIndex)
+			c.emitAttribute(fn, runtime.OpStoreAttribute, lhs.Ast1.Str0)
 		}
 
 	case AstTypeIndex:
@@ -1514,6 +1425,13 @@ func (c *AtomCompile) statement(scope *AtomScope, fn *runtime.AtomValue, ast *At
 			ast,
 		)
 
+	case AstTypeExportStatement:
+		c.exportStatement(
+			scope,
+			fn,
+			ast,
+		)
+
 	case AstTypeIfStatement:
 		c.ifStatement(
 			scope,
@@ -1571,15 +1489,6 @@ func (c *AtomCompile) breakStatement(scope *AtomScope, fn *runtime.AtomValue, as
 		return
 	}
 
-	depth := 0
-	for block := currentBlock(scope); block != nil && block.Type == AtomScopeTypeBlock; block = block.Parent {
-		depth++
-	}
-	if depth != 0 {
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpExitBlock, depth)
-	}
-
 	c.emitLine(fn, ast.Position)
 	sendBreak(scope, c.emitJump(fn, runtime.OpJump))
 }
@@ -1596,15 +1505,6 @@ func (c *AtomCompile) continueStatement(scope *AtomScope, fn *runtime.AtomValue,
 		return
 	}
 
-	depth := 0
-	for block := currentBlock(scope); block != nil && block.Type == AtomScopeTypeBlock; block = block.Parent {
-		depth++
-	}
-	if depth != 0 {
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpExitBlock, depth)
-	}
-
 	c.emitLine(fn, ast.Position)
 	sendContinue(scope, c.emitJump(fn, runtime.OpAbsoluteJump))
 }
@@ -1621,22 +1521,22 @@ func (c *AtomCompile) returnStatement(scope *AtomScope, fn *runtime.AtomValue, a
 		return
 	}
 
-	if ast.Ast0 != nil {
+	// Tail call, the callee reuses the frame of a synchronous function
+	if ast.Ast0 != nil && ast.Ast0.AstType == AstTypeCall && currentFunction(scope).Type == AtomScopeTypeFunction {
+		args := ast.Ast0.Arr0
+		for i := 0; i < len(args); i++ {
+			c.expression(scope, fn, args[i])
+		}
+		c.expression(scope, fn, ast.Ast0.Ast0)
+		c.emitLine(fn, ast.Ast0.Position)
+		c.emitInt(fn, runtime.OpTailCall, len(args))
+	} else if ast.Ast0 != nil {
 		c.expression(scope, fn, ast.Ast0)
 	} else {
 		c.emitLine(fn, ast.Position)
 		c.emit(fn, runtime.OpLoadNull)
 	}
 
-	depth := 0
-	for block := currentBlock(scope); block != nil && block.Type == AtomScopeTypeBlock; block = block.Parent {
-		depth++
-	}
-	if depth != 0 {
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpExitBlock, depth)
-	}
-
 	c.emitLine(fn, ast.Position)
 	c.emit(fn, runtime.OpReturn)
 }
@@ -1733,7 +1633,7 @@ func (c *AtomCompile) classStatement(scope *AtomScope, fn *runtime.AtomValue, as
 	}
 
 	// Save
-	c.identifier(fn, scope, name, runtime.OpStoreLocal)
+	c.identifier(fn, scope, name, runtime.OpStoreName)
 }
 
 func (c *AtomCompile) classVariable(scope *AtomScope, fn *runtime.AtomValue, ast *AtomAst) {
@@ -1788,15 +1688,15 @@ func (c *AtomCompile) classFunction(scope *AtomScope, fn *runtime.AtomValue, ast
 		scopeType = AtomScopeTypeAsyncFunction
 	}
 
-	funScope := NewAtomScope(scope, scopeType)
 	atomFunc := runtime.NewAtomGenericValue(
 		runtime.AtomTypeFunc,
 		runtime.NewAtomCode(c.parser.tokenizer.file, ast.Ast0.Str0, async, len(ast.Arr0)),
 	)
+	funScope := NewAtomFunctionScope(scope, scopeType, atomFunc)
 
 	params := ast.Arr0
 	//============================
-	fnOffset := c.state.SaveFunction(atomFunc)
+	fnOffset := c.saveFunction(atomFunc)
 	c.emitLine(fn, ast.Position)
 	c.emitInt(fn, runtime.OpLoadFunction, fnOffset)
 	c.emitLine(fn, ast.Position)
@@ -1910,15 +1810,15 @@ func (c *AtomCompile) function(scope *AtomScope, fn *runtime.AtomValue, ast *Ato
 		scopeType = AtomScopeTypeAsyncFunction
 	}
 
-	funScope := NewAtomScope(scope, scopeType)
 	atomFunc := runtime.NewAtomGenericValue(
 		runtime.AtomTypeFunc,
 		runtime.NewAtomCode(c.parser.tokenizer.file, ast.Ast0.Str0, async, len(ast.Arr0)),
 	)
+	funScope := NewAtomFunctionScope(scope, scopeType, atomFunc)
 
 	params := ast.Arr0
 	//============================
-	fnOffset := c.state.SaveFunction(atomFunc)
+	fnOffset := c.saveFunction(atomFunc)
 
 	// Save to symbol table first to allow captures to reference it
 	c.emitLine(fn, ast.Position)
@@ -1961,22 +1861,10 @@ func (c *AtomCompile) function(scope *AtomScope, fn *runtime.AtomValue, ast *Ato
 }
 
 func (c *AtomCompile) block(scope *AtomScope, fn *runtime.AtomValue, ast *AtomAst) {
-	var blockScope *AtomScope
-	var dec = hasDeclairation(ast.Arr0)
-	if dec {
-		blockScope = NewAtomScope(scope, AtomScopeTypeBlock)
-		c.emitInt(fn, runtime.OpEnterBlock, 1)
-	} else {
-		blockScope = NewAtomScope(scope, AtomScopeTypeBlockNoEnv)
-	}
-
+	blockScope := NewAtomScope(scope, AtomScopeTypeBlock)
 	for _, stmt := range ast.Arr0 {
 		c.statement(blockScope, fn, stmt)
 	}
-
-	if dec {
-		c.emitInt(fn, runtime.OpExitBlock, 1)
-	}
 }
 
 func (c *AtomCompile) varStatement(scope *AtomScope, fn *runtime.AtomValue, ast *AtomAst) {
@@ -2075,20 +1963,24 @@ func (c *AtomCompile) constStatement(scope *AtomScope, fn *runtime.AtomValue, as
 		}
 		seenNames[key.Str0] = true
 
+		// Names of the constant fold to its value when known
+		value := c.state.NullValue
 		if val == nil {
 			c.emitLine(fn, ast.Position)
 			c.emit(fn, runtime.OpLoadNull)
 		} else {
+			value = Eval(c, scope, val)
 			c.expression(scope, fn, val)
 		}
 
-		c.emitVar(
+		symbol := c.emitVar(
 			fn,
 			scope,
 			key,
 			scope.InSide(AtomScopeTypeGlobal, false) || scope.InSide(AtomScopeTypeNamespace, false),
 			true,
 		)
+		symbol.value = value
 	}
 }
 
@@ -2096,7 +1988,6 @@ func (c *AtomCompile) localStatement(scope *AtomScope, fn *runtime.AtomValue, as
 	// Guard
 	// Allowed only in block, function, async function, and loop scope
 	if !scope.InSide(AtomScopeTypeBlock, false) &&
-		!scope.InSide(AtomScopeTypeBlockNoEnv, false) &&
 		!scope.InSide(AtomScopeTypeFunction, false) &&
 		!scope.InSide(AtomScopeTypeAsyncFunction, false) &&
 		!scope.InSide(AtomScopeTypeLoop, false) {
@@ -2154,7 +2045,9 @@ func (c *AtomCompile) importStatement(scope *AtomScope, fn *runtime.AtomValue, a
 		return
 	}
 	path := ast.Ast0
+	alias := ast.Ast1
 	names := ast.Arr0
+	aliases := ast.Arr1
 	if path.AstType != AstTypeStr {
 		Error(
 			c.parser.tokenizer.file,
@@ -2165,48 +2058,9 @@ func (c *AtomCompile) importStatement(scope *AtomScope, fn *runtime.AtomValue, a
 		return
 	}
 
-	isBuiltin := func(name string) bool {
-		// match if starts with 'atom:' and followed by module name using regex
-		re := regexp.MustCompile(`^atom:([a-zA-Z_][a-zA-Z0-9_]*)$`)
-		return re.MatchString(name)
-	}
-
-	isRelative := func(name string) bool {
-		return strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../")
-	}
-
-	validIdentifier := func(name string) bool {
-		re := regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
-		return re.MatchString(name)
-	}
-
-	cleanNameWithoutExtension := func(name string) string {
-		// Remove "atom:" prefix if it exists
-		if isBuiltin(name) {
-			re := regexp.MustCompile(`^atom:([a-zA-Z_][a-zA-Z0-9_]*)$`)
-			name = re.ReplaceAllString(name, "$1")
-		} else if isRelative(name) {
-			// Remove relative path prefixes
-			for strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
-				if strings.HasPrefix(name, "../") {
-					name = strings.TrimPrefix(name, "../")
-				} else {
-					name = strings.TrimPrefix(name, "./")
-				}
-			}
-			segments := strings.Split(name, "/")
-			name = segments[len(segments)-1]
-		}
+	normalizedPath := moduleName(path.Str0)
 
-		re := regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)\.atom$`)
-		matches := re.FindStringSubmatch(name)
-		if len(matches) > 1 {
-			return matches[1]
-		}
-		return name
-	}
-
-	if !validIdentifier(cleanNameWithoutExtension(path.Str0)) {
+	if !isValidIdentifier(normalizedPath) {
 		Error(
 			c.parser.tokenizer.file,
 			c.parser.tokenizer.data,
@@ -2216,59 +2070,39 @@ func (c *AtomCompile) importStatement(scope *AtomScope, fn *runtime.AtomValue, a
 		return
 	}
 
-	normalizedPath := cleanNameWithoutExtension(path.Str0)
-
-	if isBuiltin(path.Str0) {
-		c.emitLine(fn, ast.Position)
-		c.emitStr(fn, runtime.OpLoadModule, normalizedPath)
-
-	} else if !isRelative(path.Str0) {
-		// Absolute path
-		absPath := filepath.Join(c.state.Path, "lib", normalizedPath)
-		// Check if is dir
-		if stat, error := os.Stat(absPath); error == nil && stat.IsDir() {
-			absPath = filepath.Join(absPath, "index.atom")
-			// Check if exists
-			if _, error := os.Stat(absPath); os.IsNotExist(error) {
-				Error(
-					c.parser.tokenizer.file,
-					c.parser.tokenizer.data,
-					fmt.Sprintf("Module %s not found", absPath),
-					ast.Position,
-				)
-			}
-		} else {
-			// Check if exists
-			absPath += ".atom"
-		}
+	if isBuiltinModule(path.Str0) && !c.state.IsNative(normalizedPath) {
+		Error(
+			c.parser.tokenizer.file,
+			c.parser.tokenizer.data,
+			fmt.Sprintf("Unknown module %s", path.Str0),
+			path.Position,
+		)
+		return
+	}
 
-		// Check if exists
-		if _, error := os.Stat(absPath); os.IsNotExist(error) {
+	if !isBuiltinModule(path.Str0) {
+		absPath, err := resolveModule(c.state, c.parser.tokenizer.file, path.Str0)
+		if err != nil {
 			Error(
 				c.parser.tokenizer.file,
 				c.parser.tokenizer.data,
-				fmt.Sprintf("Module %s not found", absPath),
+				err.Error(),
 				ast.Position,
 			)
 		}
 
-		// Readable?
-		if _, error := os.Stat(absPath); error != nil {
+		i, err := compileModule(c.state, normalizedPath, absPath)
+		if err != nil {
 			Error(
 				c.parser.tokenizer.file,
 				c.parser.tokenizer.data,
-				fmt.Sprintf("Module %s is not readable", absPath),
+				err.Error(),
 				ast.Position,
 			)
 		}
 
-		if exists := c.state.SaveModule(normalizedPath); !exists {
-			// Not exists, compile and export
-			t := NewAtomTokenizer(absPath, readFile(absPath))
-			p := NewAtomParser(t)
-			c := NewAtomCompile(p, c.state)
-			i := c.Export()
-
+		if i >= 0 {
+			// Not compiled before, run it and save its module
 			c.emitLine(fn, ast.Position)
 			c.emitInt(fn, runtime.OpLoadFunction, i)
 			c.emitLine(fn, ast.Position)
@@ -2278,121 +2112,54 @@ func (c *AtomCompile) importStatement(scope *AtomScope, fn *runtime.AtomValue, a
 			c.emitLine(fn, ast.Position)
 			c.emitStr(fn, runtime.OpStoreModule, normalizedPath)
 		}
+	}
 
-		c.emitLine(fn, ast.Position)
-		c.emitStr(fn, runtime.OpLoadModule, normalizedPath)
-
-	} else {
-		// Relative path or path with step?
-
-		currentPath := filepath.Dir(c.parser.tokenizer.file)
-		absPath := ""
+	c.emitLine(fn, ast.Position)
+	c.emitStr(fn, runtime.OpLoadModule, normalizedPath)
 
-		if strings.HasPrefix(path.Str0, "./") {
-			absPath = filepath.Join(currentPath, path.Str0)
-			newPath, err := filepath.Abs(absPath)
-			if err != nil {
-				Error(
-					c.parser.tokenizer.file,
-					c.parser.tokenizer.data,
-					"Failed to get absolute path",
-					ast.Position,
-				)
-			}
-			absPath = newPath
-		} else if strings.HasPrefix(path.Str0, "../") {
-			// subtract currentPath for 1 dir
-			absPath = filepath.Join(currentPath, path.Str0)
-			newPath, err := filepath.Abs(absPath)
-			if err != nil {
-				Error(
-					c.parser.tokenizer.file,
-					c.parser.tokenizer.data,
-					"Failed to get absolute path",
-					ast.Position,
-				)
-			}
-			absPath = newPath
-		} else {
-			absPath = filepath.Join(currentPath, path.Str0)
-			newPath, err := filepath.Abs(absPath)
-			if err != nil {
-				Error(
-					c.parser.tokenizer.file,
-					c.parser.tokenizer.data,
-					"Failed to get absolute path",
-					ast.Position,
-				)
-			}
-			absPath = newPath
-		}
+	seenNames := make(map[string]bool)
 
-		// Check if exists
-		if _, error := os.Stat(absPath); os.IsNotExist(error) {
-			Error(
-				c.parser.tokenizer.file,
-				c.parser.tokenizer.data,
-				fmt.Sprintf("Module %s not found", absPath),
-				ast.Position,
-			)
+	for idx, name := range names {
+		local := name
+		if aliases[idx] != nil {
+			local = aliases[idx]
 		}
 
-		// Readable?
-		if _, error := os.Stat(absPath); error != nil {
+		if name.AstType != AstTypeIdn || local.AstType != AstTypeIdn {
 			Error(
 				c.parser.tokenizer.file,
 				c.parser.tokenizer.data,
-				fmt.Sprintf("Module %s is not readable", absPath),
-				ast.Position,
+				"Expected identifier",
+				local.Position,
 			)
+			return
 		}
 
-		if exists := c.state.SaveModule(normalizedPath); !exists {
-			// Not exists, compile and export
-			t := NewAtomTokenizer(absPath, readFile(absPath))
-			p := NewAtomParser(t)
-			c := NewAtomCompile(p, c.state)
-			i := c.Export()
-
-			c.emitLine(fn, ast.Position)
-			c.emitInt(fn, runtime.OpLoadFunction, i)
-			c.emitLine(fn, ast.Position)
-			c.emitInt(fn, runtime.OpCall, 0)
-
-			// Save to table
-			c.emitLine(fn, ast.Position)
-			c.emitStr(fn, runtime.OpStoreModule, normalizedPath)
-		}
-
-		c.emitLine(fn, ast.Position)
-		c.emitStr(fn, runtime.OpLoadModule, normalizedPath)
-	}
-
-	seenNames := make(map[string]bool)
-
-	for _, name := range names {
-		if name.AstType != AstTypeIdn {
+		// Check for duplicate names
+		if seenNames[local.Str0] {
 			Error(
 				c.parser.tokenizer.file,
 				c.parser.tokenizer.data,
-				"Expected identifier",
-				name.Position,
+				fmt.Sprintf("Duplicate identifier: %s", local.Str0),
+				local.Position,
 			)
 			return
 		}
 
-		// Check for duplicate names
-		if seenNames[name.Str0] {
+		seenNames[local.Str0] = true
+
+		// Validate against the module's export list
+		if exports, exists := c.state.Exports(normalizedPath); exists && !slices.Contains(exports, name.Str0) {
 			Error(
 				c.parser.tokenizer.file,
 				c.parser.tokenizer.data,
-				fmt.Sprintf("Duplicate identifier: %s", name.Str0),
+				fmt.Sprintf("Module %s does not export %s", path.Str0, name.Str0),
 				name.Position,
 			)
 			return
 		}
 
-		seenNames[name.Str0] = true
+		c.imported[local.Str0] = true
 
 		// Save
 		c.emitLine(fn, ast.Position)
@@ -2401,26 +2168,92 @@ func (c *AtomCompile) importStatement(scope *AtomScope, fn *runtime.AtomValue, a
 		c.emitVar(
 			fn,
 			scope,
-			name,
+			local,
 			true,
 			false,
 		)
 	}
 
+	moduleName := NewTerminal(
+		AstTypeIdn,
+		normalizedPath,
+		path.Position,
+	)
+	if alias != nil {
+		if alias.AstType != AstTypeIdn {
+			Error(
+				c.parser.tokenizer.file,
+				c.parser.tokenizer.data,
+				"Expected identifier",
+				alias.Position,
+			)
+			return
+		}
+		moduleName = alias
+	}
+
+	// Already bound to the same module by an earlier import
+	if c.modules[moduleName.Str0] == normalizedPath && c.isDefined(scope, moduleName.Str0) {
+		c.emitLine(fn, ast.Position)
+		c.emit(fn, runtime.OpPopTop)
+		return
+	}
+
+	c.imported[moduleName.Str0] = true
+	c.modules[moduleName.Str0] = normalizedPath
+
 	// Save to table
 	c.emitVar(
 		fn,
 		scope,
-		NewTerminal(
-			AstTypeIdn,
-			normalizedPath,
-			path.Position,
-		),
+		moduleName,
 		true,
 		false,
 	)
 }
 
+func (c *AtomCompile) exportStatement(scope *AtomScope, fn *runtime.AtomValue, ast *AtomAst) {
+	// Guard
+	// Allowed only in global scope
+	if !scope.InSide(AtomScopeTypeGlobal, false) {
+		Error(
+			c.parser.tokenizer.file,
+			c.parser.tokenizer.data,
+			"Export statement must be in global scope",
+			ast.Position,
+		)
+		return
+	}
+
+	declaration := ast.Ast0
+	c.statement(scope, fn, declaration)
+
+	names := []*AtomAst{}
+	switch declaration.AstType {
+	case AstTypeVarStatement,
+		AstTypeConstStatement:
+		names = append(names, declaration.Arr0...)
+	default:
+		names = append(names, declaration.Ast0)
+	}
+
+	// Once a module exports something explicitly, only
+	// the exported names are visible to importers
+	c.exported = true
+	for _, name := range names {
+		if slices.Contains(c.exports, name.Str0) {
+			Error(
+				c.parser.tokenizer.file,
+				c.parser.tokenizer.data,
+				fmt.Sprintf("Duplicate export: %s", name.Str0),
+				name.Position,
+			)
+			return
+		}
+		c.exports = append(c.exports, name.Str0)
+	}
+}
+
 func (c *AtomCompile) ifStatement(scope *AtomScope, fn *runtime.AtomValue, ast *AtomAst) {
 	c.expression(scope, fn, ast.Ast0)
 	c.emitLine(fn, ast.Position)
@@ -2500,93 +2333,43 @@ func (c *AtomCompile) switchStatement(scope *AtomScope, fn *runtime.AtomValue, a
 	}
 }
 
+// loopBody compiles the body of a loop, the locals declared
+// in a block body are declared again on every iteration.
+func (c *AtomCompile) loopBody(loopScope *AtomScope, fn *runtime.AtomValue, body *AtomAst) {
+	if body.AstType == AstTypeBlock {
+		c.block(loopScope, fn, body)
+		return
+	}
+	single := NewAtomScope(loopScope, AtomScopeTypeSingle)
+	c.statement(single, fn, body)
+}
+
 func (c *AtomCompile) whileStatement(scope *AtomScope, fn *runtime.AtomValue, ast *AtomAst) {
 	loopScope := NewAtomScope(scope, AtomScopeTypeLoop)
 	loopStart := c.here(fn)
-	if ast.Ast1.AstType == AstTypeBlock && hasDeclairation(ast.Ast1.Arr0) {
-		// Condition
-		c.expression(loopScope, fn, ast.Ast0)
-
-		// Check
-		c.emitLine(fn, ast.Position)
-		toEnd := c.emitJump(fn, runtime.OpPopJumpIfFalse)
-
-		// Body
-		blockScope := NewAtomScope(loopScope, AtomScopeTypeBlock)
-		c.emitInt(fn, runtime.OpEnterBlock, 1)
-
-		for _, stmt := range ast.Ast1.Arr0 {
-			c.statement(blockScope, fn, stmt)
-		}
-
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
-
-		// Exit block
-		if len(loopScope.Continues) == 0 {
-			c.emitInt(fn, runtime.OpExitBlock, 1)
-		}
-
-		// Loop start
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
 
-		// End loop
-		c.label(fn, toEnd)
-	} else if ast.Ast1.AstType == AstTypeBlock {
-		// Condition
-		c.expression(loopScope, fn, ast.Ast0)
-
-		// Check
-		c.emitLine(fn, ast.Position)
-		toEnd := c.emitJump(fn, runtime.OpPopJumpIfFalse)
-
-		// Body
-		blockScope := NewAtomScope(loopScope, AtomScopeTypeBlockNoEnv)
-		for _, stmt := range ast.Ast1.Arr0 {
-			c.statement(blockScope, fn, stmt)
-		}
-
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
-
-		// Loop start
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
-
-		// End loop
-		c.label(fn, toEnd)
-	} else {
-		// Condition
-		c.expression(loopScope, fn, ast.Ast0)
+	// Condition
+	c.expression(loopScope, fn, ast.Ast0)
 
-		// Check
-		c.emitLine(fn, ast.Position)
-		toEnd := c.emitJump(fn, runtime.OpPopJumpIfFalse)
+	// Check
+	c.emitLine(fn, ast.Position)
+	toEnd := c.emitJump(fn, runtime.OpPopJumpIfFalse)
 
-		// Body
-		single := NewAtomScope(loopScope, AtomScopeTypeSingle)
-		c.statement(single, fn, ast.Ast1)
+	// Body
+	c.loopBody(loopScope, fn, ast.Ast1)
 
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
+	// Modify opcodes for continue
+	for _, continueAddress := range loopScope.Continues {
+		fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
+		c.label(fn, continueAddress)
+	}
 
-		// Loop start
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
+	// Loop start
+	c.emitLine(fn, ast.Position)
+	c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
 
-		// End loop
-		c.label(fn, toEnd)
-	}
+	// End loop
+	c.label(fn, toEnd)
 	for _, breakAddress := range loopScope.Breaks {
 		c.label(fn, breakAddress)
 	}
@@ -2595,89 +2378,29 @@ func (c *AtomCompile) whileStatement(scope *AtomScope, fn *runtime.AtomValue, as
 func (c *AtomCompile) doWhileStatement(scope *AtomScope, fn *runtime.AtomValue, ast *AtomAst) {
 	loopScope := NewAtomScope(scope, AtomScopeTypeLoop)
 	loopStart := c.here(fn)
-	if ast.Ast1.AstType == AstTypeBlock && hasDeclairation(ast.Ast1.Arr0) {
-		blockScope := NewAtomScope(loopScope, AtomScopeTypeBlock)
-		c.emitInt(fn, runtime.OpEnterBlock, 1)
-
-		// Body
-		for _, stmt := range ast.Ast1.Arr0 {
-			c.statement(blockScope, fn, stmt)
-		}
-
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
-
-		if len(loopScope.Continues) == 0 {
-			c.emitInt(fn, runtime.OpExitBlock, 1)
-		}
-
-		// Condition
-		c.expression(loopScope, fn, ast.Ast0)
-
-		// Check
-		c.emitLine(fn, ast.Position)
-		toEnd := c.emitJump(fn, runtime.OpPopJumpIfFalse)
-
-		// Loop start
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
-
-		// End loop
-		c.label(fn, toEnd)
-
-	} else if ast.Ast1.AstType == AstTypeBlock {
-		blockScope := NewAtomScope(loopScope, AtomScopeTypeBlockNoEnv)
-		for _, stmt := range ast.Ast1.Arr0 {
-			c.statement(blockScope, fn, stmt)
-		}
-
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
-
-		// Condition
-		c.expression(loopScope, fn, ast.Ast0)
 
-		// Check
-		c.emitLine(fn, ast.Position)
-		toEnd := c.emitJump(fn, runtime.OpPopJumpIfFalse)
-
-		// Loop start
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
-
-		// End loop
-		c.label(fn, toEnd)
-	} else {
-		single := NewAtomScope(loopScope, AtomScopeTypeSingle)
-		// Body
-		c.statement(single, fn, ast.Ast1)
+	// Body
+	c.loopBody(loopScope, fn, ast.Ast1)
 
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
+	// Modify opcodes for continue
+	for _, continueAddress := range loopScope.Continues {
+		fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
+		c.label(fn, continueAddress)
+	}
 
-		// Condition
-		c.expression(loopScope, fn, ast.Ast0)
+	// Condition
+	c.expression(loopScope, fn, ast.Ast0)
 
-		// Check
-		c.emitLine(fn, ast.Position)
-		toEnd := c.emitJump(fn, runtime.OpPopJumpIfFalse)
+	// Check
+	c.emitLine(fn, ast.Position)
+	toEnd := c.emitJump(fn, runtime.OpPopJumpIfFalse)
 
-		// Loop start
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
+	// Loop start
+	c.emitLine(fn, ast.Position)
+	c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
 
-		// End loop
-		c.label(fn, toEnd)
-	}
+	// End loop
+	c.label(fn, toEnd)
 	for _, breakAddress := range loopScope.Breaks {
 		c.label(fn, breakAddress)
 	}
@@ -2704,95 +2427,29 @@ func (c *AtomCompile) forStatement(scope *AtomScope, fn *runtime.AtomValue, ast
 		jump0 = c.emitJump(fn, runtime.OpPopJumpIfFalse)
 	}
 
-	// body
-	single := NewAtomScope(loopScope, AtomScopeTypeSingle)
-	if body.AstType == AstTypeBlock && hasDeclairation(body.Arr0) {
-		blockScope := NewAtomScope(loopScope, AtomScopeTypeBlock)
-
-		c.emitInt(fn, runtime.OpEnterBlock, 1)
-
-		for _, stmt := range body.Arr0 {
-			c.statement(blockScope, fn, stmt)
-		}
-
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
-
-		// Exit block
-		if len(loopScope.Continues) == 0 {
-			c.emitInt(fn, runtime.OpExitBlock, 1)
-		}
-
-		// Updater
-		if updater != nil {
-			c.expression(loopScope, fn, updater)
-			c.emitLine(fn, updater.Position)
-			c.emit(fn, runtime.OpPopTop)
-		}
-
-		// Loop
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
-
-		// End loop
-		if condition != nil {
-			c.label(fn, jump0)
-		}
-
-	} else if body.AstType == AstTypeBlock {
-		blockScope := NewAtomScope(loopScope, AtomScopeTypeBlockNoEnv)
-		for _, stmt := range body.Arr0 {
-			c.statement(blockScope, fn, stmt)
-		}
-
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
-
-		// Updater
-		if updater != nil {
-			c.expression(loopScope, fn, updater)
-			c.emitLine(fn, updater.Position)
-			c.emit(fn, runtime.OpPopTop)
-		}
-
-		// Loop
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
-
-		// End loop
-		if condition != nil {
-			c.label(fn, jump0)
-		}
-	} else {
-		c.statement(single, fn, body)
+	// Body
+	c.loopBody(loopScope, fn, body)
 
-		// Modify opcodes for continue
-		for _, continueAddress := range loopScope.Continues {
-			fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
-			c.label(fn, continueAddress)
-		}
+	// Modify opcodes for continue
+	for _, continueAddress := range loopScope.Continues {
+		fn.Obj.(*runtime.AtomCode).Code[continueAddress-1] = runtime.OpJump
+		c.label(fn, continueAddress)
+	}
 
-		// Updater
-		if updater != nil {
-			c.expression(loopScope, fn, updater)
-			c.emitLine(fn, updater.Position)
-			c.emit(fn, runtime.OpPopTop)
-		}
+	// Updater
+	if updater != nil {
+		c.expression(loopScope, fn, updater)
+		c.emitLine(fn, updater.Position)
+		c.emit(fn, runtime.OpPopTop)
+	}
 
-		// Loop
-		c.emitLine(fn, ast.Position)
-		c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
+	// Loop
+	c.emitLine(fn, ast.Position)
+	c.emitInt(fn, runtime.OpAbsoluteJump, loopStart)
 
-		// End loop
-		if condition != nil {
-			c.label(fn, jump0)
-		}
+	// End loop
+	if condition != nil {
+		c.label(fn, jump0)
 	}
 
 	for _, breakAddress := range loopScope.Breaks {
@@ -2800,12 +2457,50 @@ func (c *AtomCompile) forStatement(scope *AtomScope, fn *runtime.AtomValue, ast
 	}
 }
 
+// resolve checks the names that were not declared yet when they were
+// compiled. They must be declared by the end of the program, before
+// their use when the program itself uses them, and constants are
+// never assigned.
+func (c *AtomCompile) resolve(globalScope *AtomScope, programFunc *runtime.AtomValue) {
+	for _, pendingVariable := range c.pendingVariables {
+		ast := pendingVariable.ast
+		message := ""
+		switch {
+		case !c.isDefined(globalScope, ast.Str0):
+			message = fmt.Sprintf("Variable %s is not defined", ast.Str0)
+		case pendingVariable.store && c.lookup(globalScope, ast.Str0).constant:
+			message = "Cannot store to constant variable"
+		case pendingVariable.atomFunc == programFunc:
+			// Functions run later, the program runs in order
+			message = fmt.Sprintf("Variable %s is used before its declaration", ast.Str0)
+		default:
+			continue
+		}
+		Error(
+			c.parser.tokenizer.file,
+			c.parser.tokenizer.data,
+			message,
+			ast.Position,
+		)
+	}
+}
+
 func (c *AtomCompile) program(ast *AtomAst) *runtime.AtomValue {
-	globalScope := NewAtomScope(nil, AtomScopeTypeGlobal)
+	// Globals defined by the host or by an earlier run live in
+	// an outer scope, so the program may still redeclare them
+	var hostScope *AtomScope = nil
+	if len(c.predefined) > 0 {
+		hostScope = NewAtomScope(nil, AtomScopeTypeGlobal)
+		for _, name := range c.predefined {
+			hostScope.Names[name] = NewAtomSymbol(name, true, false)
+		}
+	}
 	programFunc := runtime.NewAtomGenericValue(
 		runtime.AtomTypeFunc,
 		runtime.NewAtomCode(c.parser.tokenizer.file, "script", false, 0),
 	)
+	programFunc.Obj.(*runtime.AtomCode).Global = true
+	globalScope := NewAtomFunctionScope(hostScope, AtomScopeTypeGlobal, programFunc)
 	body := ast.Arr1
 	for _, stmt := range body {
 		c.statement(globalScope, programFunc, stmt)
@@ -2815,22 +2510,10 @@ func (c *AtomCompile) program(ast *AtomAst) *runtime.AtomValue {
 	c.emitLine(programFunc, ast.Position)
 	c.emit(programFunc, runtime.OpReturn)
 
-	// Resolve
-	for _, pendingVariable := range c.pendingVariables {
-		/*
-		 * Variables that have been referenced but do not exist yet,
-		 * we mark them as global captured variables
-		 */
-		if !c.isDefined(globalScope, pendingVariable.ast.Str0) {
-			Error(
-				c.parser.tokenizer.file,
-				c.parser.tokenizer.data,
-				fmt.Sprintf("Variable %s is not defined", pendingVariable.ast.Str0),
-				pendingVariable.ast.Position,
-			)
-		}
-	}
+	c.resolve(globalScope, programFunc)
 
+	c.optimize(programFunc)
+	c.verify(programFunc)
 	return programFunc
 }
 
@@ -2839,50 +2522,45 @@ func (c *AtomCompile) Export() int {
 	if exists := c.state.SaveModule(c.parser.tokenizer.file); exists {
 		panic("Already exists (not handled properly)!")
 	}
-	globalScope := NewAtomScope(nil, AtomScopeTypeGlobal)
 	programFunc := runtime.NewAtomGenericValue(
 		runtime.AtomTypeFunc,
 		runtime.NewAtomCode(c.parser.tokenizer.file, "script", false, 0),
 	)
+	programFunc.Obj.(*runtime.AtomCode).Global = true
+	globalScope := NewAtomFunctionScope(nil, AtomScopeTypeGlobal, programFunc)
 	body := ast.Arr1
 	for _, stmt := range body {
 		c.statement(globalScope, programFunc, stmt)
 	}
 
-	// Get global vars
-	count := 0
-	for _, name := range globalScope.Names {
-		if !name.global {
-			continue
+	// Without an explicit export, every global declared in
+	// the module is public unless its name starts with '_'
+	if !c.exported {
+		for _, name := range globalScope.Names {
+			if !name.global || c.imported[name.name] || strings.HasPrefix(name.name, "_") {
+				continue
+			}
+			c.exports = append(c.exports, name.name)
 		}
-		count++
+		sort.Strings(c.exports)
+	}
+
+	for _, name := range c.exports {
 		c.emitLine(programFunc, ast.Position)
-		c.emitStr(programFunc, runtime.OpLoadName, name.name)
+		c.emitStr(programFunc, runtime.OpLoadName, name)
 		c.emitLine(programFunc, ast.Position)
-		c.emitStr(programFunc, runtime.OpLoadStr, name.name)
+		c.emitStr(programFunc, runtime.OpLoadStr, name)
 	}
 	c.emitLine(programFunc, ast.Position)
-	c.emitInt(programFunc, runtime.OpMakeModule, count)
+	c.emitInt(programFunc, runtime.OpMakeModule, len(c.exports))
 
 	c.emitLine(programFunc, ast.Position)
 	c.emit(programFunc, runtime.OpReturn)
 
-	// Resolve
-	for _, pendingVariable := range c.pendingVariables {
-		/*
-		 * Variables that have been referenced but do not exist yet,
-		 * we mark them as global captured variables
-		 */
-		if !c.isDefined(globalScope, pendingVariable.ast.Str0) {
-			Error(
-				c.parser.tokenizer.file,
-				c.parser.tokenizer.data,
-				fmt.Sprintf("Variable %s is not defined", pendingVariable.ast.Str0),
-				pendingVariable.ast.Position,
-			)
-		}
-	}
+	c.resolve(globalScope, programFunc)
 
+	c.optimize(programFunc)
+	c.verify(programFunc)
 	return c.state.SaveFunction(programFunc)
 }
 
@@ -0,0 +1,109 @@
+package atom
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	runtime "dev.runtime"
+)
+
+const counterScript = `
+import [sqrt, floor] from "atom:math";
+import [toUpper] from "atom:string";
+import [hello, repeat] from "atom:greet";
+
+class Counter {
+    func init(self, name) {
+        self.name = name;
+        self.count = 0;
+    }
+
+    func add(self, amount) {
+        self.count = self.count + amount;
+        return self;
+    }
+}
+
+var total = 0;
+var label = "";
+
+func work(rounds) {
+    local counter = new Counter(toUpper(name));
+    for (local i = 0; i < rounds; i++) {
+        counter.add(id);
+        total = total + floor(sqrt(i * i));
+    }
+    label = hello(counter.name) + repeat("!", id);
+    return counter.count;
+}
+`
+
+// TestParallelVMs runs a VM per goroutine, run it with -race.
+func TestParallelVMs(t *testing.T) {
+	const vms = 8
+	const rounds = 2000
+	// Registered once, each VM gets its own copy
+	module := newGreetModule()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, vms)
+	for id := 1; id <= vms; id++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- runCounter(module, id, rounds)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func runCounter(module *runtime.AtomModule, id int, rounds int) error {
+	vm := New(AtomOptions{TierThreshold: 100})
+	if err := vm.RegisterModule(module); err != nil {
+		return err
+	}
+	if err := vm.SetGlobal("id", id); err != nil {
+		return err
+	}
+	if err := vm.SetGlobal("name", fmt.Sprintf("vm%d", id)); err != nil {
+		return err
+	}
+	if err := vm.RunString(counterScript); err != nil {
+		return err
+	}
+
+	// Twice, the second call runs the tiered code with warm caches
+	for range 2 {
+		count, err := vm.Call("work", rounds)
+		if err != nil {
+			return err
+		}
+		if count != id*rounds {
+			return fmt.Errorf("vm %d: count = %v, want %d", id, count, id*rounds)
+		}
+	}
+
+	total, err := vm.GetGlobal("total")
+	if err != nil {
+		return err
+	}
+	if want := rounds * (rounds - 1); total != want {
+		return fmt.Errorf("vm %d: total = %v, want %d", id, total, want)
+	}
+	label, err := vm.GetGlobal("label")
+	if err != nil {
+		return err
+	}
+	if want := fmt.Sprintf("Hello, VM%d", id) + strings.Repeat("!", id); label != want {
+		return fmt.Errorf("vm %d: label = %q, want %q", id, label, want)
+	}
+	return nil
+}
@@ -1,13 +1,32 @@
-package main
+package atom
 
 import (
 	"fmt"
 	"math"
-	"os"
 	"runtime"
 	"strings"
 )
 
+// AtomCompileError is raised by Error for tokenizer, parser and
+// compiler failures, the message carries the highlighted source.
+type AtomCompileError struct {
+	Message string
+}
+
+func (e *AtomCompileError) Error() string {
+	return e.Message
+}
+
+func recoverCompileError(err *error) {
+	if r := recover(); r != nil {
+		compileError, ok := r.(*AtomCompileError)
+		if !ok {
+			panic(r)
+		}
+		*err = compileError
+	}
+}
+
 func Error(file string, data []rune, message string, position AtomPosition) {
 	pc, _, _, ok := runtime.Caller(1) // 1 = caller frame
 	caller := ""
@@ -69,6 +88,5 @@ func Error(file string, data []rune, message string, position AtomPosition) {
 		}
 	}
 
-	fmt.Print(err_message)
-	os.Exit(1)
+	panic(&AtomCompileError{Message: err_message})
 }
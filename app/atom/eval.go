@@ -0,0 +1,253 @@
+package atom
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	runtime "dev.runtime"
+)
+
+// foldOps maps the operators Eval folds to the opcode computing them.
+var foldOps = map[AtomAstType]runtime.OpCode{
+	AstTypeUnaryBitNot:            runtime.OpBitNot,
+	AstTypeUnaryNot:               runtime.OpNot,
+	AstTypeUnaryNeg:               runtime.OpNeg,
+	AstTypeUnaryPos:               runtime.OpPos,
+	AstTypeUnaryTypeof:            runtime.OpTypeof,
+	AstTypeBinaryMul:              runtime.OpMul,
+	AstTypeBinaryDiv:              runtime.OpDiv,
+	AstTypeBinaryMod:              runtime.OpMod,
+	AstTypeBinaryAdd:              runtime.OpAdd,
+	AstTypeBinarySub:              runtime.OpSub,
+	AstTypeBinaryShiftRight:       runtime.OpShr,
+	AstTypeBinaryShiftLeft:        runtime.OpShl,
+	AstTypeBinaryGreaterThan:      runtime.OpCmpGt,
+	AstTypeBinaryGreaterThanEqual: runtime.OpCmpGte,
+	AstTypeBinaryLessThan:         runtime.OpCmpLt,
+	AstTypeBinaryLessThanEqual:    runtime.OpCmpLte,
+	AstTypeBinaryEqual:            runtime.OpCmpEq,
+	AstTypeBinaryNotEqual:         runtime.OpCmpNe,
+	AstTypeBinaryAnd:              runtime.OpAnd,
+	AstTypeBinaryOr:               runtime.OpOr,
+	AstTypeBinaryXor:              runtime.OpXor,
+}
+
+// isFoldable reports whether ast is an expression Eval may reduce to
+// a constant, literals are loaded as they are.
+func isFoldable(ast *AtomAst) bool {
+	if _, ok := foldOps[ast.AstType]; ok {
+		return true
+	}
+	switch ast.AstType {
+	case AstTypeIdn,
+		AstTypeLogicalAnd,
+		AstTypeLogicalOr,
+		AstTypeIfExpression,
+		AstTypeSwitchExpression:
+		return true
+	default:
+		return false
+	}
+}
+
+// literal returns the value of an Int, Num, Str, Bool or Null node.
+// Integers outside of int32 become numbers and the n suffix makes a
+// big integer.
+func (c *AtomCompile) literal(ast *AtomAst) *runtime.AtomValue {
+	switch ast.AstType {
+	case AstTypeInt:
+		if strings.HasSuffix(ast.Str0, "n") || strings.HasSuffix(ast.Str0, "N") {
+			str := strings.TrimSuffix(ast.Str0, "n")
+			str = strings.TrimSuffix(str, "N")
+			return runtime.NewAtomValueBigInt(runtime.BigInt(str))
+		}
+
+		intValue, err := strconv.Atoi(ast.Str0)
+		var overflowed bool
+		if after, ok := strings.CutPrefix(ast.Str0, "0x"); ok {
+			_intValue, _err := strconv.ParseInt(after, 16, 64)
+			if _intValue > math.MaxInt32 || _intValue < math.MinInt32 {
+				overflowed = true
+			} else {
+				intValue = int(_intValue)
+			}
+			err = _err
+		} else if after, ok := strings.CutPrefix(ast.Str0, "0o"); ok {
+			_intValue, _err := strconv.ParseInt(after, 8, 64)
+			if _intValue > math.MaxInt32 || _intValue < math.MinInt32 {
+				overflowed = true
+			} else {
+				intValue = int(_intValue)
+			}
+			err = _err
+		} else if after, ok := strings.CutPrefix(ast.Str0, "0b"); ok {
+			_intValue, _err := strconv.ParseInt(after, 2, 64)
+			if _intValue > math.MaxInt32 || _intValue < math.MinInt32 {
+				overflowed = true
+			} else {
+				intValue = int(_intValue)
+			}
+			err = _err
+		} else {
+			// Check for overflow in decimal case
+			_intValue, _err := strconv.ParseInt(ast.Str0, 10, 64)
+			if _err == nil && (_intValue > math.MaxInt32 || _intValue < math.MinInt32) {
+				overflowed = true
+			}
+		}
+
+		// If overflow detected, promote to float
+		if overflowed {
+			numValue, numErr := strconv.ParseFloat(ast.Str0, 64)
+			if numErr != nil {
+				Error(
+					c.parser.tokenizer.file,
+					c.parser.tokenizer.data,
+					"Invalid number",
+					ast.Position,
+				)
+			}
+			return runtime.NewAtomValueNum(numValue)
+		}
+
+		if err != nil {
+			Error(
+				c.parser.tokenizer.file,
+				c.parser.tokenizer.data,
+				"Invalid integer",
+				ast.Position,
+			)
+		}
+		return runtime.NewAtomValueInt(intValue)
+
+	case AstTypeNum:
+		numValue, err := strconv.ParseFloat(ast.Str0, 64)
+		if err != nil {
+			Error(
+				c.parser.tokenizer.file,
+				c.parser.tokenizer.data,
+				"Invalid number",
+				ast.Position,
+			)
+		}
+		return runtime.NewAtomValueNum(numValue)
+
+	case AstTypeStr:
+		return runtime.NewAtomValueStr(ast.Str0)
+
+	case AstTypeBool:
+		if ast.Str0 == "true" {
+			return c.state.TrueValue
+		}
+		return c.state.FalseValue
+
+	default:
+		return c.state.NullValue
+	}
+}
+
+// Eval folds a constant expression to the value the runtime computes
+// for it. Operators run the runtime Do* operations through
+// runtime.Fold, names fold to the value of a const initialized with a
+// constant. It returns nil when ast is not constant or evaluating it
+// fails, the error is then left to the runtime.
+func Eval(compiler *AtomCompile, scope *AtomScope, ast *AtomAst) *runtime.AtomValue {
+	if ast == nil {
+		return nil
+	}
+	switch ast.AstType {
+	case AstTypeInt,
+		AstTypeNum,
+		AstTypeStr,
+		AstTypeBool,
+		AstTypeNull:
+		return compiler.literal(ast)
+
+	case AstTypeIdn:
+		if !compiler.isDefined(scope, ast.Str0) {
+			return nil
+		}
+		symbol := compiler.lookup(scope, ast.Str0)
+		if !symbol.constant {
+			return nil
+		}
+		return symbol.value
+
+	case AstTypeLogicalAnd, AstTypeLogicalOr:
+		// Both sides must be constant, the names of the right
+		// side are checked when it is compiled
+		lhs := Eval(compiler, scope, ast.Ast0)
+		if lhs == nil {
+			return nil
+		}
+		rhs := Eval(compiler, scope, ast.Ast1)
+		if rhs == nil {
+			return nil
+		}
+		if runtime.CoerceToBool(lhs) == (ast.AstType == AstTypeLogicalAnd) {
+			return rhs
+		}
+		return lhs
+
+	case AstTypeIfExpression:
+		condition := Eval(compiler, scope, ast.Ast0)
+		thenValue := Eval(compiler, scope, ast.Ast1)
+		elseValue := Eval(compiler, scope, ast.Ast2)
+		if condition == nil || thenValue == nil || elseValue == nil {
+			return nil
+		}
+		if runtime.CoerceToBool(condition) {
+			return thenValue
+		}
+		return elseValue
+
+	case AstTypeSwitchExpression:
+		condition := Eval(compiler, scope, ast.Ast0)
+		result := Eval(compiler, scope, ast.Ast1)
+		if condition == nil || result == nil {
+			return nil
+		}
+		// Every case is evaluated, the first match wins like
+		// OpPeekJumpIfEqual comparing hash values
+		matched := false
+		for index, caseArray := range ast.Arr0 {
+			value := Eval(compiler, scope, ast.Arr1[index])
+			if value == nil {
+				return nil
+			}
+			for _, caseItem := range caseArray.Arr0 {
+				pattern := Eval(compiler, scope, caseItem)
+				if pattern == nil {
+					return nil
+				}
+				if !matched && pattern.HashValue() == condition.HashValue() {
+					matched = true
+					result = value
+				}
+			}
+		}
+		return result
+	}
+
+	op, ok := foldOps[ast.AstType]
+	if !ok {
+		return nil
+	}
+	if ast.Ast1 == nil {
+		operand := Eval(compiler, scope, ast.Ast0)
+		if operand == nil {
+			return nil
+		}
+		return runtime.Fold(compiler.state, op, operand)
+	}
+	lhs := Eval(compiler, scope, ast.Ast0)
+	if lhs == nil {
+		return nil
+	}
+	rhs := Eval(compiler, scope, ast.Ast1)
+	if rhs == nil {
+		return nil
+	}
+	return runtime.Fold(compiler.state, op, lhs, rhs)
+}
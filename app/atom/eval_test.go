@@ -0,0 +1,45 @@
+package atom
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFoldedDivisionMatchesRuntime(t *testing.T) {
+	// Negative literals are numbers, subtractions keep integers
+	divisions := map[string]any{
+		"7 / 2":                          3,
+		"(0 - 7) / 2":                    -3,
+		"8 / 2":                          4,
+		"7.0 / 2":                        3.5,
+		"(0 - 2147483647 - 1) / (0 - 1)": 2147483648.0,
+	}
+	for expression, want := range divisions {
+		file := filepath.Join(t.TempDir(), "fold.atom")
+		if err := os.WriteFile(file, []byte("var folded = "+expression+";"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		vm := New(AtomOptions{})
+		disassembly, err := vm.Disassemble(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(disassembly, "DIV") {
+			t.Fatalf("%s is not folded:\n%s", expression, disassembly)
+		}
+
+		lhs, rhs, _ := strings.Cut(expression, " / ")
+		mustRun(t, vm, `
+func divide(a, b) { return a / b; }
+var folded = `+expression+`;
+var unfolded = divide(`+lhs+`, `+rhs+`);
+`)
+		folded, _ := vm.GetGlobal("folded")
+		unfolded, _ := vm.GetGlobal("unfolded")
+		if folded != want || unfolded != want {
+			t.Errorf("%s: folded %v (%T), unfolded %v (%T), want %v (%T)", expression, folded, folded, unfolded, unfolded, want, want)
+		}
+	}
+}
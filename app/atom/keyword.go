@@ -1,4 +1,4 @@
-package main
+package atom
 
 const (
 	KeyClass    = "class"
@@ -11,6 +11,8 @@ const (
 	KeyEnum     = "enum"
 	KeyImport   = "import"
 	KeyFrom     = "from"
+	KeyExport   = "export"
+	KeyAs       = "as"
 	KeyContinue = "continue"
 	KeyBreak    = "break"
 	KeyReturn   = "return"
@@ -0,0 +1,115 @@
+package atom
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	runtime "dev.runtime"
+)
+
+const spinForever = `
+func spin() {
+    local n = 0;
+    while (true) { n = n + 1; }
+}
+`
+
+func expectAborted(t *testing.T, err error, cause error) {
+	t.Helper()
+	if !errors.Is(err, runtime.ErrExecutionAborted) || !errors.Is(err, cause) {
+		t.Fatalf("error %v, want an abort caused by %v", err, cause)
+	}
+}
+
+func TestInstructionLimit(t *testing.T) {
+	vm := New(AtomOptions{Limits: runtime.AtomLimits{Instructions: 10000}})
+	expectAborted(t, vm.RunString(spinForever+`spin();`), runtime.ErrInstructionLimit)
+
+	// The budget is per run, the VM stays usable
+	mustRun(t, vm, `var done = true;`)
+}
+
+func TestTimeout(t *testing.T) {
+	vm := New(AtomOptions{Limits: runtime.AtomLimits{Timeout: 50 * time.Millisecond}})
+	expectAborted(t, vm.RunString(spinForever+`spin();`), context.DeadlineExceeded)
+
+	// Sleeps wake up early
+	start := time.Now()
+	expectAborted(t, vm.RunString(`
+import [sleep] from "atom:std";
+sleep(10000);
+`), context.DeadlineExceeded)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("sleep aborted after %v", elapsed)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	vm := New(AtomOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	expectAborted(t, vm.RunStringContext(ctx, spinForever+`spin();`), context.Canceled)
+}
+
+func TestScriptsCannotCatchAborts(t *testing.T) {
+	vm := New(AtomOptions{Limits: runtime.AtomLimits{Instructions: 10000}})
+	expectAborted(t, vm.RunString(spinForever+`
+var caught = false;
+spin() catch(err) { caught = true; };
+`), runtime.ErrInstructionLimit)
+
+	if caught, _ := vm.GetGlobal("caught"); caught != false {
+		t.Fatalf("caught = %v", caught)
+	}
+}
+
+func TestCallContextAborts(t *testing.T) {
+	vm := New(AtomOptions{})
+	mustRun(t, vm, spinForever)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := vm.CallContext(ctx, "spin")
+	expectAborted(t, err, context.DeadlineExceeded)
+}
+
+func TestMemoryLimit(t *testing.T) {
+	vm := New(AtomOptions{Limits: runtime.AtomLimits{Memory: 1 << 20}})
+	mustRun(t, vm, `var small = [1, 2, 3];`)
+	if usage := vm.MemoryUsage(); usage <= 0 || usage > 1<<20 {
+		t.Fatalf("usage = %d", usage)
+	}
+
+	expectAborted(t, vm.RunString(`
+var items = [];
+while (true) { items.push("item " + items.length()); }
+`), runtime.ErrMemoryLimit)
+	if usage := vm.MemoryUsage(); usage <= 1<<20 {
+		t.Fatalf("usage after the abort = %d", usage)
+	}
+
+	// Each run starts from zero
+	mustRun(t, vm, `small = [4, 5, 6];`)
+}
+
+// TestMemoryLimitCountsNativeResults checks that the values a builtin
+// builds inside its result are counted, not only the result itself.
+func TestMemoryLimitCountsNativeResults(t *testing.T) {
+	vm := New(AtomOptions{Limits: runtime.AtomLimits{Memory: 100 << 10}})
+	// Globals set by the host are not counted
+	if err := vm.SetGlobal("text", strings.Repeat("a,", 10000)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The array alone is about 80KB, with its 10001 strings about 500KB
+	expectAborted(t, vm.RunString(`
+import [split] from "atom:string";
+var parts = split(text, ",");
+`), runtime.ErrMemoryLimit)
+}
@@ -0,0 +1,161 @@
+package atom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	runtime "dev.runtime"
+)
+
+var (
+	builtinModulePattern = regexp.MustCompile(`^atom:([a-zA-Z_][a-zA-Z0-9_]*)$`)
+	identifierPattern    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	moduleFilePattern    = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)\.atom$`)
+)
+
+func isBuiltinModule(path string) bool {
+	// match if starts with 'atom:' and followed by module name
+	return builtinModulePattern.MatchString(path)
+}
+
+func isRelativeModule(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
+}
+
+func isValidIdentifier(name string) bool {
+	return identifierPattern.MatchString(name)
+}
+
+// moduleName strips the "atom:" prefix, relative steps and
+// the ".atom" extension, leaving the name the module is bound to.
+func moduleName(path string) string {
+	name := path
+	if isBuiltinModule(name) {
+		name = builtinModulePattern.ReplaceAllString(name, "$1")
+	} else if isRelativeModule(name) {
+		// Remove relative path prefixes
+		for strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+			if strings.HasPrefix(name, "../") {
+				name = strings.TrimPrefix(name, "../")
+			} else {
+				name = strings.TrimPrefix(name, "./")
+			}
+		}
+		segments := strings.Split(name, "/")
+		name = segments[len(segments)-1]
+	}
+
+	matches := moduleFilePattern.FindStringSubmatch(name)
+	if len(matches) > 1 {
+		return matches[1]
+	}
+	return name
+}
+
+// resolveModule finds the file behind a non builtin import path.
+// Relative paths start from the importing file and must stay inside the
+// sandbox roots, anything else is looked up in the lib directory next
+// to the executable.
+func resolveModule(state *runtime.AtomState, file string, path string) (string, error) {
+	absPath := ""
+
+	if !isRelativeModule(path) {
+		absPath = filepath.Join(state.Path, "lib", moduleName(path))
+		// Check if is dir
+		if stat, err := os.Stat(absPath); err == nil && stat.IsDir() {
+			absPath = filepath.Join(absPath, "index.atom")
+		} else {
+			absPath += ".atom"
+		}
+	} else {
+		newPath, err := filepath.Abs(filepath.Join(filepath.Dir(file), path))
+		if err != nil {
+			return "", fmt.Errorf("Failed to get absolute path")
+		}
+		absPath = newPath
+		// Scripts may only import files inside the sandbox roots
+		if err := state.Sandbox.CheckPath(absPath); err != nil {
+			return "", err
+		}
+	}
+
+	// Check if exists
+	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("Module %s not found", absPath)
+	}
+
+	// Readable?
+	if _, err := os.Stat(absPath); err != nil {
+		return "", fmt.Errorf("Module %s is not readable", absPath)
+	}
+
+	return absPath, nil
+}
+
+// loadModule compiles a module requested by a dynamic import.
+// The offset is -1 when the module was already compiled.
+func loadModule(state *runtime.AtomState, file string, path string) (name string, offset int, err error) {
+	defer recoverCompileError(&err)
+
+	name = moduleName(path)
+	if !isValidIdentifier(name) {
+		return name, -1, fmt.Errorf("Invalid module name %s", path)
+	}
+
+	absPath, err := resolveModule(state, file, path)
+	if err != nil {
+		return name, -1, err
+	}
+
+	offset, err = compileModule(state, name, absPath)
+	if err != nil {
+		return name, -1, err
+	}
+
+	return name, offset, nil
+}
+
+// compileModule compiles the module file at absPath bound to name, the
+// offset is -1 when it was already compiled. A module that fails to
+// compile is forgotten, so importing it again reports the error again.
+func compileModule(state *runtime.AtomState, name string, absPath string) (offset int, err error) {
+	if exists := state.SaveModule(name); exists {
+		return -1, nil
+	}
+	_, known := state.ModuleLookup[absPath]
+	failed := true
+	defer func() {
+		if !failed {
+			return
+		}
+		state.RemoveModule(name)
+		if !known {
+			state.RemoveModule(absPath)
+		}
+	}()
+
+	content, err := readFile(absPath)
+	if err != nil {
+		return -1, err
+	}
+
+	t := NewAtomTokenizer(absPath, content)
+	p := NewAtomParser(t)
+	c := NewAtomCompile(p, state)
+	offset = c.Export()
+	state.SaveExports(name, c.exports)
+	failed = false
+
+	return offset, nil
+}
+
+func readFile(file string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
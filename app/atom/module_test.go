@@ -0,0 +1,92 @@
+package atom
+
+import (
+	"strings"
+	"testing"
+
+	runtime "dev.runtime"
+)
+
+func newGreetModule() *runtime.AtomModule {
+	return runtime.NewAtomModule("greet").
+		Define("version", runtime.NewAtomValueInt(1)).
+		Define("settings", runtime.NewAtomGenericValue(runtime.AtomTypeObj, runtime.NewAtomObject(map[string]*runtime.AtomValue{
+			"punctuation": runtime.NewAtomValueStr("!"),
+		}))).
+		DefineFunc("hello", 1, func(interpreter *runtime.AtomInterpreter, frame *runtime.AtomCallFrame, argc int) {
+			name := frame.Stack.Pop()
+			frame.Stack.Push(runtime.NewAtomValueStr("Hello, " + name.String()))
+		}).
+		Bind("repeat", func(text string, times int) string {
+			return strings.Repeat(text, times)
+		})
+}
+
+func TestRegisterModule(t *testing.T) {
+	vm := New(AtomOptions{})
+	if err := vm.RegisterModule(newGreetModule()); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, vm, `
+import [hello, repeat, version] from "atom:greet";
+var message = hello("Atom") + " " + repeat("ab", 2) + " v" + version;
+`)
+
+	message, err := vm.GetGlobal("message")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != "Hello, Atom abab v1" {
+		t.Fatalf("message = %v", message)
+	}
+}
+
+func TestRegisterModuleRejectsNames(t *testing.T) {
+	vm := New(AtomOptions{})
+	if err := vm.RegisterModule(runtime.NewAtomModule("not-valid")); err == nil {
+		t.Fatal("an invalid module name should be rejected")
+	}
+	if err := vm.RegisterModule(runtime.NewAtomModule("math")); err == nil {
+		t.Fatal("a builtin module should not be replaced")
+	}
+	if err := vm.RegisterModule(newGreetModule()); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.RegisterModule(newGreetModule()); err == nil {
+		t.Fatal("a module should not be registered twice")
+	}
+}
+
+func TestRegisteredModulesArePerVM(t *testing.T) {
+	module := newGreetModule()
+	first := New(AtomOptions{})
+	second := New(AtomOptions{})
+	other := New(AtomOptions{})
+	for _, vm := range []*AtomVM{first, second} {
+		if err := vm.RegisterModule(module); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Values are copied, changes stay in the VM that made them
+	mustRun(t, first, `
+import [settings] from "atom:greet";
+settings.punctuation = "?";
+`)
+	mustRun(t, second, `
+import [settings] from "atom:greet";
+var punctuation = settings.punctuation;
+`)
+	punctuation, err := second.GetGlobal("punctuation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if punctuation != "!" {
+		t.Fatalf("punctuation = %v, want !", punctuation)
+	}
+
+	err = other.RunString(`import [hello] from "atom:greet";`)
+	if err == nil || !strings.Contains(err.Error(), "Unknown module atom:greet") {
+		t.Fatalf("import from a VM without the module: %v", err)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package atom
 
 import (
 	"fmt"
@@ -230,10 +230,21 @@ func (p *AtomParser) primary() *AtomAst {
 			body,
 			start.Merge(ended),
 		)
+	} else if p.checkT(TokenTypeKey) && p.checkV(KeyImport) {
+		p.acceptV(KeyImport)
+		return p.importCall(start)
 	}
 	return p.terminal()
 }
 
+func (p *AtomParser) importCall(start AtomPosition) *AtomAst {
+	p.acceptV("(")
+	path := p.mandatory()
+	ended := p.lookahead.Position
+	p.acceptV(")")
+	return NewImport(path, start.Merge(ended))
+}
+
 func (p *AtomParser) memberOrCall() *AtomAst {
 	ast := p.primary()
 	if ast == nil {
@@ -959,6 +970,8 @@ func (p *AtomParser) statement() *AtomAst {
 		return p.block()
 	} else if p.checkT(TokenTypeKey) && p.checkV(KeyImport) {
 		return p.importStatement()
+	} else if p.checkT(TokenTypeKey) && p.checkV(KeyExport) {
+		return p.exportStatement()
 	} else if p.checkT(TokenTypeKey) && p.checkV(KeyVar) {
 		return p.varStatement()
 	} else if p.checkT(TokenTypeKey) && p.checkV(KeyConst) {
@@ -1189,11 +1202,23 @@ func (p *AtomParser) importStatement() *AtomAst {
 
 	p.acceptV(KeyImport)
 
+	// Dynamic import used as a statement
+	if p.checkT(TokenTypeSym) && p.checkV("(") {
+		expr := p.importCall(start)
+		ended = p.lookahead.Position
+		p.acceptV(";")
+		return NewExpressionStatement(
+			expr,
+			expr.Position.Merge(ended),
+		)
+	}
+
 	names := []*AtomAst{}
+	aliases := []*AtomAst{}
 	var path *AtomAst = nil
+	var alias *AtomAst = nil
 
-	if p.checkT(TokenTypeSym) && p.checkV("[") {
-		p.acceptV("[")
+	importName := func() bool {
 		nameN := p.terminal()
 		if nameN == nil {
 			Error(
@@ -1202,22 +1227,37 @@ func (p *AtomParser) importStatement() *AtomAst {
 				"Expected identifier",
 				p.lookahead.Position,
 			)
-			return nil
+			return false
 		}
-		names = append(names, nameN)
-		for p.checkT(TokenTypeSym) && p.checkV(",") {
-			p.acceptV(",")
-			nameN = p.terminal()
-			if nameN == nil {
+		var aliasN *AtomAst = nil
+		if p.checkT(TokenTypeKey) && p.checkV(KeyAs) {
+			p.acceptV(KeyAs)
+			aliasN = p.terminal()
+			if aliasN == nil {
 				Error(
 					p.tokenizer.file,
 					p.tokenizer.data,
 					"Expected identifier",
 					p.lookahead.Position,
 				)
+				return false
+			}
+		}
+		names = append(names, nameN)
+		aliases = append(aliases, aliasN)
+		return true
+	}
+
+	if p.checkT(TokenTypeSym) && p.checkV("[") {
+		p.acceptV("[")
+		if !importName() {
+			return nil
+		}
+		for p.checkT(TokenTypeSym) && p.checkV(",") {
+			p.acceptV(",")
+			if !importName() {
 				return nil
 			}
-			names = append(names, nameN)
 		}
 		p.acceptV("]")
 
@@ -1227,16 +1267,67 @@ func (p *AtomParser) importStatement() *AtomAst {
 
 	path = p.terminal()
 
+	if p.checkT(TokenTypeKey) && p.checkV(KeyAs) {
+		p.acceptV(KeyAs)
+		alias = p.terminal()
+		if alias == nil {
+			Error(
+				p.tokenizer.file,
+				p.tokenizer.data,
+				"Expected identifier",
+				p.lookahead.Position,
+			)
+			return nil
+		}
+	}
+
 	ended = p.lookahead.Position
 	p.acceptV(";")
 
 	return NewImportStatement(
 		path,
+		alias,
 		names,
+		aliases,
 		start.Merge(ended),
 	)
 }
 
+func (p *AtomParser) exportStatement() *AtomAst {
+	start := p.lookahead.Position
+
+	p.acceptV(KeyExport)
+
+	var declaration *AtomAst = nil
+
+	if p.checkT(TokenTypeKey) && p.checkV(KeyClass) {
+		declaration = p.classStatement()
+	} else if p.checkT(TokenTypeKey) && p.checkV(KeyEnum) {
+		declaration = p.enumStatement()
+	} else if p.checkT(TokenTypeKey) && p.checkV(KeyAsync) {
+		declaration = p.function(true)
+	} else if p.checkT(TokenTypeKey) && p.checkV(KeyFunc) {
+		declaration = p.function(false)
+	} else if p.checkT(TokenTypeKey) && p.checkV(KeyVar) {
+		declaration = p.varStatement()
+	} else if p.checkT(TokenTypeKey) && p.checkV(KeyConst) {
+		declaration = p.constStatement()
+	} else {
+		Error(
+			p.tokenizer.file,
+			p.tokenizer.data,
+			"Expected declaration after export",
+			p.lookahead.Position,
+		)
+		return nil
+	}
+
+	return NewExportStatement(
+		declaration,
+		start.Merge(declaration.Position),
+	)
+}
+
 func (p *AtomParser) varStatement() *AtomAst {
 	start := p.lookahead.Position
 	ended := start
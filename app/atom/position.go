@@ -1,13 +1,13 @@
-package main
+package atom
 
 /*
  * Export everything for Compiler
  */
 type AtomPosition struct {
-	LineStart int
-	LineEnded int
-	ColmStart int
-	ColmEnded int
+	LineStart int `json:"lineStart"`
+	LineEnded int `json:"lineEnded"`
+	ColmStart int `json:"colmStart"`
+	ColmEnded int `json:"colmEnded"`
 }
 
 func (p *AtomPosition) Merge(other AtomPosition) AtomPosition {
@@ -0,0 +1,133 @@
+package atom
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	runtime "dev.runtime"
+)
+
+// writeFiles creates the files of contents under dir.
+func writeFiles(t *testing.T, dir string, contents map[string]string) {
+	t.Helper()
+	for name, content := range contents {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newSandboxedVM(t *testing.T, sandbox runtime.AtomSandbox) *AtomVM {
+	t.Helper()
+	vm := New(AtomOptions{})
+	if err := vm.Restrict(sandbox); err != nil {
+		t.Fatal(err)
+	}
+	return vm
+}
+
+func expectRunError(t *testing.T, err error, message string) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), message) {
+		t.Fatalf("error %v, want %q", err, message)
+	}
+}
+
+func TestSandboxModules(t *testing.T) {
+	vm := newSandboxedVM(t, runtime.AtomSandbox{Modules: []string{"std", "math", "path.join"}})
+
+	mustRun(t, vm, `
+import [sqrt] from "atom:math";
+import [join] from "atom:path";
+var root = sqrt(16);
+var joined = join("a", "b");
+`)
+	if root, _ := vm.GetGlobal("root"); root != 4.0 {
+		t.Fatalf("root = %v", root)
+	}
+
+	expectRunError(t, vm.RunString(`import [exec] from "atom:os";`), "module os is not allowed")
+	mustRun(t, vm, `
+async func load() {
+    local message = "";
+    await import("atom:os") catch(err) { message = "" + err; };
+    return message;
+}
+`)
+	if message, err := vm.Call("load"); err != nil || !strings.Contains(message.(string), "module os is not allowed") {
+		t.Fatalf("dynamic import: %v %v", message, err)
+	}
+	expectRunError(t, vm.RunString(`
+import [throw] from "atom:std";
+import [cwd] from "atom:path";
+cwd() catch(err) { throw(err); };
+`), "path.cwd: access denied")
+}
+
+func TestSandboxNoExec(t *testing.T) {
+	vm := newSandboxedVM(t, runtime.AtomSandbox{NoExec: true})
+	expectRunError(t, vm.RunString(`
+import [throw] from "atom:std";
+import [exec] from "atom:os";
+exec("true") catch(err) { throw(err); };
+`), "os.exec: process execution is disabled")
+}
+
+func TestSandboxRoots(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	writeFiles(t, dir, map[string]string{
+		"root/data.txt":    "inside",
+		"outside/data.txt": "outside",
+	})
+	if err := os.Symlink(filepath.Join(dir, "outside"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	vm := newSandboxedVM(t, runtime.AtomSandbox{Roots: []string{root}})
+	if err := vm.SetGlobal("root", root); err != nil {
+		t.Fatal(err)
+	}
+
+	mustRun(t, vm, `
+import [read] from "atom:file";
+var content = read(root + "/data.txt", "string");
+`)
+	if content, _ := vm.GetGlobal("content"); content != "inside" {
+		t.Fatalf("content = %v", content)
+	}
+
+	for _, path := range []string{"/../outside/data.txt", "/link/data.txt"} {
+		err := vm.RunString(`
+import [throw] from "atom:std";
+import [read] from "atom:file";
+read(root + "` + path + `", "string") catch(err) { throw(err); };
+`)
+		expectRunError(t, err, "is denied")
+	}
+}
+
+func TestSandboxRelativeImports(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	writeFiles(t, dir, map[string]string{
+		"root/inside.atom":      `import [helper] from "./lib/answers.atom"; var value = helper();`,
+		"root/lib/answers.atom": `export func helper() { return 42; }`,
+		"root/escape.atom":      `import [secret] from "../outside/secret.atom";`,
+		"outside/secret.atom":   `export var secret = 1;`,
+	})
+	vm := newSandboxedVM(t, runtime.AtomSandbox{Roots: []string{root}})
+
+	if err := vm.RunFile(filepath.Join(root, "inside.atom")); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := vm.GetGlobal("value"); value != 42 {
+		t.Fatalf("value = %v", value)
+	}
+	expectRunError(t, vm.RunFile(filepath.Join(root, "escape.atom")), "is denied")
+}
@@ -1,4 +1,6 @@
-package main
+package atom
+
+import runtime "dev.runtime"
 
 type AtomScopeType int
 
@@ -9,7 +11,6 @@ const (
 	AtomScopeTypeAsyncFunction
 	AtomScopeTypeNamespace
 	AtomScopeTypeBlock
-	AtomScopeTypeBlockNoEnv
 	AtomScopeTypeLoop
 	AtomScopeTypeSingle
 )
@@ -21,6 +22,8 @@ type AtomScope struct {
 	Names     map[string]*AtomSymbol
 	Continues []int
 	Breaks    []int
+	Function  *runtime.AtomValue  // Code of a function or global scope
+	Captures  map[*AtomSymbol]int // Cells captured by Function
 }
 
 func NewAtomScope(parent *AtomScope, scopeType AtomScopeType) *AtomScope {
@@ -31,6 +34,8 @@ func NewAtomScope(parent *AtomScope, scopeType AtomScopeType) *AtomScope {
 		Names:     map[string]*AtomSymbol{},
 		Continues: []int{},
 		Breaks:    []int{},
+		Function:  nil,
+		Captures:  nil,
 	}
 }
 
@@ -42,9 +47,20 @@ func NewAtomAliasScope(parent *AtomScope, scopeType AtomScopeType, alias string)
 		Names:     map[string]*AtomSymbol{},
 		Continues: []int{},
 		Breaks:    []int{},
+		Function:  nil,
+		Captures:  nil,
 	}
 }
 
+// NewAtomFunctionScope creates the scope compiled into function,
+// its locals get slots in the frames of function.
+func NewAtomFunctionScope(parent *AtomScope, scopeType AtomScopeType, function *runtime.AtomValue) *AtomScope {
+	scope := NewAtomScope(parent, scopeType)
+	scope.Function = function
+	scope.Captures = map[*AtomSymbol]int{}
+	return scope
+}
+
 func (s *AtomScope) InSide(scope AtomScopeType, recurse bool) bool {
 	current := s
 	for current != nil {
@@ -0,0 +1,27 @@
+package atom
+
+import runtime "dev.runtime"
+
+type AtomSymbol struct {
+	name     string
+	global   bool
+	constant bool
+	slot     int                // Index in the locals of owner, globals have none
+	owner    *runtime.AtomValue // Function declaring the local
+	captured bool               // Used by a closure, the local lives in a cell
+	uses     []int              // Addresses of the local's instructions in owner
+	value    *runtime.AtomValue // Folded initializer of a constant, nil if not constant
+}
+
+func NewAtomSymbol(name string, global bool, constant bool) *AtomSymbol {
+	return &AtomSymbol{
+		name:     name,
+		global:   global,
+		constant: constant,
+		slot:     -1,
+		owner:    nil,
+		captured: false,
+		uses:     []int{},
+		value:    nil,
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package atom
 
 import (
 	"slices"
@@ -30,7 +30,7 @@ func NewAtomTokenizer(file string, data string) *AtomTokenizer {
 func (t *AtomTokenizer) isKeyword(word string) bool {
 	keywords := []string{
 		KeyClass, KeyExtends, KeyAsync, KeyFunc, KeyVar, KeyConst, KeyLocal, KeyEnum,
-		KeyImport, KeyFrom, KeyContinue, KeyBreak, KeyReturn,
+		KeyImport, KeyFrom, KeyExport, KeyAs, KeyContinue, KeyBreak, KeyReturn,
 		KeyIf, KeyElse, KeySwitch, KeyCase, KeyDefault, KeyCatch, KeyFor,
 		KeyWhile, KeyDo, KetTrue, KetFalse, KetNull, KeyNew, KeyTypeof, KeyAwait, KeyBase,
 	}
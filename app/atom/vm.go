@@ -0,0 +1,220 @@
+package atom
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	runtime "dev.runtime"
+)
+
+// AtomOptions configures a virtual machine created with New.
+type AtomOptions struct {
+	// Path is the directory holding the lib folder used by absolute
+	// imports, it defaults to the directory of the running executable.
+	Path string
+	// Limits bounds every run and call, see runtime.AtomLimits.
+	Limits runtime.AtomLimits
+	// MaxCallDepth overrides runtime.DefaultMaxCallDepth, a negative
+	// value disables the check.
+	MaxCallDepth int
+	// NoOptimize compiles scripts without the bytecode optimizer,
+	// keeping the instructions close to the source for debugging.
+	NoOptimize bool
+	// TierThreshold overrides runtime.TRESHOLD, the calls and loop
+	// iterations after which a function runs as threaded code.
+	// A negative value keeps every function in the bytecode switch.
+	TierThreshold int
+}
+
+// AtomVM hosts Atom scripts inside a Go program. Globals declared by a
+// script stay alive between runs, so later scripts and the host can use them.
+type AtomVM struct {
+	state       *runtime.AtomState
+	interpreter *runtime.AtomInterpreter
+	globals     *runtime.AtomEnv
+}
+
+func New(options AtomOptions) *AtomVM {
+	state := runtime.NewAtomState()
+	if options.Path != "" {
+		state.Path = options.Path
+	}
+	state.Loader = loadModule
+	state.Optimize = !options.NoOptimize
+	interpreter := runtime.NewInterpreter(state)
+	interpreter.Limits = options.Limits
+	if options.MaxCallDepth != 0 {
+		interpreter.MaxCallDepth = max(options.MaxCallDepth, 0)
+	}
+	if options.TierThreshold != 0 {
+		interpreter.Threshold = max(options.TierThreshold, 0)
+	}
+	return &AtomVM{
+		state:       state,
+		interpreter: interpreter,
+		globals:     runtime.NewAtomEnv(nil),
+	}
+}
+
+func (vm *AtomVM) RunString(source string) error {
+	return vm.RunStringContext(context.Background(), source)
+}
+
+func (vm *AtomVM) RunFile(file string) error {
+	return vm.RunFileContext(context.Background(), file)
+}
+
+// RunStringContext is RunString aborted when ctx is done, the error
+// then matches runtime.ErrExecutionAborted.
+func (vm *AtomVM) RunStringContext(ctx context.Context, source string) error {
+	return vm.run(ctx, "<string>", source)
+}
+
+func (vm *AtomVM) RunFileContext(ctx context.Context, file string) error {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	content, err := readFile(absPath)
+	if err != nil {
+		return err
+	}
+	return vm.run(ctx, absPath, content)
+}
+
+// Call invokes a global function with arguments converted from Go
+// and converts its result back, async functions are awaited.
+func (vm *AtomVM) Call(name string, args ...any) (any, error) {
+	return vm.CallContext(context.Background(), name, args...)
+}
+
+func (vm *AtomVM) CallContext(ctx context.Context, name string, args ...any) (any, error) {
+	if !vm.globals.Has(name) {
+		return nil, fmt.Errorf("%s is not defined", name)
+	}
+	values := make([]*runtime.AtomValue, len(args))
+	for i, arg := range args {
+		value, err := runtime.Marshal(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		values[i] = value
+	}
+	result, err := vm.interpreter.CallContext(ctx, vm.globals.Get(name), values...)
+	if err != nil {
+		return nil, err
+	}
+	var converted any
+	if err := runtime.Unmarshal(result, &converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+func (vm *AtomVM) SetGlobal(name string, value any) error {
+	if !isValidIdentifier(name) {
+		return fmt.Errorf("invalid global name %s", name)
+	}
+	converted, err := runtime.Marshal(value)
+	if err != nil {
+		return err
+	}
+	vm.globals.Put(name, converted)
+	return nil
+}
+
+func (vm *AtomVM) GetGlobal(name string) (any, error) {
+	var value any
+	if err := vm.GetGlobalInto(name, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// GetGlobalInto stores a global in the Go value target points to,
+// see runtime.Unmarshal.
+func (vm *AtomVM) GetGlobalInto(name string, target any) error {
+	if !vm.globals.Has(name) {
+		return fmt.Errorf("%s is not defined", name)
+	}
+	return runtime.Unmarshal(vm.globals.Get(name), target)
+}
+
+// MemoryUsage is the approximate number of bytes allocated by the
+// current run, or by the last one.
+func (vm *AtomVM) MemoryUsage() int64 {
+	return vm.interpreter.MemoryUsage()
+}
+
+// Restrict sandboxes the scripts of this VM, see runtime.AtomSandbox.
+func (vm *AtomVM) Restrict(sandbox runtime.AtomSandbox) error {
+	return vm.interpreter.Restrict(sandbox)
+}
+
+// RegisterModule makes a native module importable as "atom:<name>"
+// by the scripts of this VM.
+func (vm *AtomVM) RegisterModule(module *runtime.AtomModule) error {
+	return vm.interpreter.RegisterModule(module)
+}
+
+// Disassemble compiles a file without running it and returns the
+// text of its program and of every function in the function table,
+// see runtime.Disassemble.
+func (vm *AtomVM) Disassemble(file string) (string, error) {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	content, err := readFile(absPath)
+	if err != nil {
+		return "", err
+	}
+	program, err := vm.compile(absPath, content)
+	if err != nil {
+		return "", err
+	}
+	return runtime.Disassemble(program.Obj.(*runtime.AtomCode), vm.state.FunctionTable), nil
+}
+
+// RunAssembly assembles a file written in the text format of
+// Disassemble and runs it, see runtime.Assemble.
+func (vm *AtomVM) RunAssembly(file string) error {
+	return vm.RunAssemblyContext(context.Background(), file)
+}
+
+func (vm *AtomVM) RunAssemblyContext(ctx context.Context, file string) error {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	content, err := readFile(absPath)
+	if err != nil {
+		return err
+	}
+	program, err := runtime.Assemble(vm.state, absPath, content)
+	if err != nil {
+		return err
+	}
+	return vm.interpreter.RunContext(ctx, program, vm.globals)
+}
+
+func (vm *AtomVM) run(ctx context.Context, file string, source string) error {
+	program, err := vm.compile(file, source)
+	if err != nil {
+		return err
+	}
+	return vm.interpreter.RunContext(ctx, program, vm.globals)
+}
+
+func (vm *AtomVM) compile(file string, source string) (program *runtime.AtomValue, err error) {
+	defer recoverCompileError(&err)
+
+	t := NewAtomTokenizer(file, source)
+	p := NewAtomParser(t)
+	c := NewAtomCompile(p, vm.state)
+	for name := range vm.globals.Locals {
+		c.predefined = append(c.predefined, name)
+	}
+	return c.Compile(), nil
+}
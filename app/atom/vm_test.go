@@ -0,0 +1,149 @@
+package atom
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustRun runs source on vm and fails the test on error.
+func mustRun(t *testing.T, vm *AtomVM, source string) {
+	t.Helper()
+	if err := vm.RunString(source); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
+
+func TestCallUsesHostGlobals(t *testing.T) {
+	vm := New(AtomOptions{})
+	if err := vm.SetGlobal("limit", 10); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, vm, `func double(x) { return x * limit; }`)
+
+	result, err := vm.Call("double", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 40 {
+		t.Fatalf("double(4) = %v, want 40", result)
+	}
+}
+
+func TestCallAwaitsAsyncFunctions(t *testing.T) {
+	vm := New(AtomOptions{})
+	mustRun(t, vm, `async func greet(name) { return "Hello, " + name; }`)
+
+	result, err := vm.Call("greet", "Atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "Hello, Atom" {
+		t.Fatalf("greet = %v", result)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	vm := New(AtomOptions{})
+	mustRun(t, vm, `
+import [throw] from "atom:std";
+func fail() { throw("broken"); }
+`)
+
+	if _, err := vm.Call("missing"); err == nil || !strings.Contains(err.Error(), "missing is not defined") {
+		t.Fatalf("missing function: %v", err)
+	}
+	if _, err := vm.Call("fail"); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("throwing function: %v", err)
+	}
+	if _, err := vm.Call("fail", make(chan int)); err == nil || !strings.Contains(err.Error(), "argument 1") {
+		t.Fatalf("unconvertible argument: %v", err)
+	}
+}
+
+func TestGlobalsOutliveRuns(t *testing.T) {
+	vm := New(AtomOptions{})
+	mustRun(t, vm, `var counter = 1;`)
+	mustRun(t, vm, `counter = counter + 1;`)
+
+	counter, err := vm.GetGlobal("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter != 2 {
+		t.Fatalf("counter = %v, want 2", counter)
+	}
+
+	if _, err := vm.GetGlobal("missing"); err == nil {
+		t.Fatal("reading an undefined global should fail")
+	}
+	if err := vm.SetGlobal("not valid", 1); err == nil {
+		t.Fatal("an invalid global name should be rejected")
+	}
+}
+
+func TestGetGlobalInto(t *testing.T) {
+	type config struct {
+		Host  string   `atom:"host"`
+		Port  int      `atom:"port"`
+		Tags  []string `atom:"tags"`
+		Token string   `atom:"-"`
+	}
+
+	vm := New(AtomOptions{})
+	if err := vm.SetGlobal("config", config{Host: "localhost", Port: 8080, Token: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, vm, `
+config.port = config.port + 1;
+config.tags = ["a", "b"];
+`)
+
+	var got config
+	if err := vm.GetGlobalInto("config", &got); err != nil {
+		t.Fatal(err)
+	}
+	want := config{Host: "localhost", Port: 8081, Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("config = %+v, want %+v", got, want)
+	}
+}
+
+// TestAssemblyVerify runs the assembly of test/verify, which the
+// verifier must reject before any of it executes.
+func TestAssemblyVerify(t *testing.T) {
+	failures := map[string]string{
+		"fallthrough.asm": "in noreturn at 5: POP_TOP runs off the end of the code without RETURN",
+		"jumpend.asm":     "in jumpend at 1: JUMP runs off the end of the code without RETURN",
+	}
+	for file, message := range failures {
+		err := New(AtomOptions{}).RunAssembly(filepath.Join("..", "test", "verify", file))
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: error %v, want %q", file, err, message)
+		}
+	}
+}
+
+// TestBuiltinsCoerceNumbers checks that the builtins taking a count or
+// a code still accept any number and truncate it.
+func TestBuiltinsCoerceNumbers(t *testing.T) {
+	vm := New(AtomOptions{})
+	mustRun(t, vm, `
+import [rand] from "atom:math";
+import [response] from "atom:GinBinding";
+var roll = rand(1.5);
+var status = response(201.7, "created").status;
+`)
+	if roll, _ := vm.GetGlobal("roll"); roll != 0 {
+		t.Errorf("roll = %v", roll)
+	}
+	if status, _ := vm.GetGlobal("status"); status != 201 {
+		t.Errorf("status = %v (%T)", status, status)
+	}
+	expectRunError(t, vm.RunString(`
+import [throw] from "atom:std";
+import [rand] from "atom:math";
+rand("3") catch(err) { throw(err); };
+`), "expects number for argument 1")
+}
@@ -2,10 +2,12 @@ module dev.atom
 
 go 1.24.5
 
-require dev.runtime v0.0.0
+require (
+	dev.runtime v0.0.0
+	github.com/fatih/color v1.18.0
+)
 
 require (
-	github.com/fatih/color v1.18.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	golang.org/x/sys v0.25.0 // indirect
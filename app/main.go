@@ -1,25 +1,20 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	gruntime "runtime"
+	"strconv"
 	"strings"
 
-	runtime "dev.runtime"
+	"dev.atom/atom"
+	"github.com/fatih/color"
 )
 
-func readFile(file string) string {
-	content, err := os.ReadFile(file)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
-	}
-	return string(content)
-}
-
-func runTests(testFile string) {
+func runTests(testFile string, options atom.AtomOptions) {
 	execPath, err := os.Executable()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -33,7 +28,7 @@ func runTests(testFile string) {
 		fileDir := filepath.Join(testsDir, testFile)
 
 		// Add .atom extension if not present
-		if !strings.HasSuffix(testFile, ".atom") {
+		if !strings.HasSuffix(testFile, ".atom") && !strings.HasSuffix(testFile, ".asm") {
 			fileDir += ".atom"
 		}
 
@@ -43,7 +38,7 @@ func runTests(testFile string) {
 			os.Exit(1)
 		}
 
-		runFile(fileDir)
+		runTest(fileDir, options)
 		return
 	}
 
@@ -58,7 +53,7 @@ func runTests(testFile string) {
 	total := 0
 
 	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".atom") {
+		if file.IsDir() || !(strings.HasSuffix(file.Name(), ".atom") || strings.HasSuffix(file.Name(), ".asm")) {
 			continue
 		}
 
@@ -66,13 +61,22 @@ func runTests(testFile string) {
 		testPath := filepath.Join(testsDir, file.Name())
 
 		// We could add error handling here to continue testing even if one test fails
-		runFile(testPath)
+		runTest(testPath, options)
 		success++
 	}
 
 	fmt.Printf("Success: %d Total: %d\n", success, total)
 }
 
+// runTest runs a test script, or a test written in assembly.
+func runTest(file string, options atom.AtomOptions) {
+	if strings.HasSuffix(file, ".asm") {
+		runAssembly(file, options)
+		return
+	}
+	runFile(file, options)
+}
+
 func printStartupBanner() {
 	fmt.Println("╔══════════════════════════════════════════════════════════════════════════════╗")
 	fmt.Println("║                                                                              ║")
@@ -92,45 +96,130 @@ func printStartupBanner() {
 	fmt.Println("║  GitHub:   https://github.com/HolliShake/atomv3                              ║")
 	fmt.Printf("║  Version:  %s                                                             ║\n", VERSION)
 	fmt.Println("║                                                                              ║")
-	fmt.Println("║  usage: atom [<file.atom> | --test]                                          ║")
+	fmt.Println("║  usage: atom [-O0] [-tier=<n>] [<file.atom> | --test]                        ║")
+	fmt.Println("║         atom [-O0] disasm <file.atom>                                        ║")
+	fmt.Println("║         atom [-tier=<n>] asm <file.asm>                                      ║")
+	fmt.Println("║         atom parse --json <file.atom>                                        ║")
 	fmt.Println("╚══════════════════════════════════════════════════════════════════════════════╝")
 }
 
-func runFile(file string) {
-	code := readFile(file)
-	s := runtime.NewAtomState()
-	t := NewAtomTokenizer(file, code)
-	p := NewAtomParser(t)
-	c := NewAtomCompile(p, s)
-	f := c.Compile()
-	i := runtime.NewInterpreter(s)
-	i.Interpret(f)
+func runFile(file string, options atom.AtomOptions) {
+	vm := atom.New(options)
+	if err := vm.RunFile(file); err != nil {
+		printError(err)
+	}
+}
+
+func printError(err error) {
+	var compileError *atom.AtomCompileError
+	if errors.As(err, &compileError) {
+		fmt.Print(compileError.Error())
+	} else {
+		fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+	}
+	os.Exit(1)
+}
+
+// disassemble prints the bytecode of a script without running it.
+func disassemble(file string, options atom.AtomOptions) {
+	vm := atom.New(options)
+	text, err := vm.Disassemble(file)
+	if err != nil {
+		printError(err)
+	}
+	fmt.Println(text)
+}
+
+// runAssembly runs a program written in the text format of disasm.
+func runAssembly(file string, options atom.AtomOptions) {
+	vm := atom.New(options)
+	if err := vm.RunAssembly(file); err != nil {
+		printError(err)
+	}
+}
+
+// parse prints the syntax tree of a script as JSON.
+func parse(file string) {
+	ast, err := atom.ParseFile(file)
+	if err != nil {
+		printError(err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(ast); err != nil {
+		printError(err)
+	}
 }
 
 func main() {
-	if len(os.Args) < 2 {
+	// -O0 turns the bytecode optimizer off, -tier=<n> runs functions
+	// as threaded code after n calls or loop iterations, 0 never does
+	options := atom.AtomOptions{}
+	args := []string{}
+	for _, arg := range os.Args[1:] {
+		if arg == "-O0" {
+			options.NoOptimize = true
+			continue
+		}
+		if value, found := strings.CutPrefix(arg, "-tier="); found {
+			threshold, err := strconv.Atoi(value)
+			if err != nil || threshold < 0 {
+				fmt.Fprintln(os.Stderr, "invalid tier threshold: "+value)
+				os.Exit(1)
+			}
+			options.TierThreshold = threshold
+			if threshold == 0 {
+				options.TierThreshold = -1
+			}
+			continue
+		}
+		args = append(args, arg)
+	}
+
+	if len(args) < 1 {
 		printStartupBanner()
 		os.Exit(1)
 	}
 
-	if os.Args[1] == "--test" {
+	if args[0] == "--test" {
 		testFile := ""
-		if len(os.Args) > 2 {
-			testFile = os.Args[2]
+		if len(args) > 1 {
+			testFile = args[1]
 		}
-		runTests(testFile)
+		runTests(testFile, options)
+		os.Exit(0)
+	}
+
+	if (args[0] == "disasm" || args[0] == "asm") && len(args) < 2 {
+		printStartupBanner()
+		os.Exit(1)
+	}
+	if args[0] == "parse" && (len(args) < 3 || args[1] != "--json") {
+		printStartupBanner()
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "parse":
+		parse(args[2])
+		os.Exit(0)
+	case "disasm":
+		disassemble(args[1], options)
+		os.Exit(0)
+	case "asm":
+		runAssembly(args[1], options)
 		os.Exit(0)
 	}
 
 	gruntime.GC()
 	var mStart, mEnd gruntime.MemStats
-	absPath, err := filepath.Abs(os.Args[1])
+	absPath, err := filepath.Abs(args[0])
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 
-	runFile(absPath)
+	runFile(absPath, options)
 
 	gruntime.ReadMemStats(&mEnd)
 	fmt.Printf("💾 Memory usage: %d kilobytes\n", (mEnd.Alloc-mStart.Alloc)/1024)
@@ -0,0 +1,12 @@
+; Function 0 runs off the end of its code instead of returning, the
+; verifier rejects it before the caller uses the missing result.
+; Checked by TestAssemblyVerify, running it must fail.
+
+.program "fallthrough" argc=0 locals=0 global
+    LOAD_FUNCTION 0
+    CALL 0
+    RETURN
+
+.function 0 "noreturn" argc=0 locals=0
+    LOAD_INT 1
+    POP_TOP
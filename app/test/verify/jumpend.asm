@@ -0,0 +1,8 @@
+; A jump to the end of the code leaves it without returning.
+; Checked by TestAssemblyVerify, running it must fail.
+
+.program "jumpend" argc=0 locals=0 global
+    LOAD_NULL
+    JUMP L_end
+    RETURN
+L_end:
@@ -0,0 +1,152 @@
+; Instructions checked one by one, without the compiler choosing them.
+; Run with: atom asm test/vm.asm
+
+.program "vm" argc=0 locals=0 global
+.line 1
+    LOAD_MODULE "std"
+    PLUCK_ATTRIBUTE "println"
+    INIT_NAME "println"
+    PLUCK_ATTRIBUTE "throw"
+    INIT_NAME "throw"
+    POP_TOP
+
+; ROT2 swaps the two values on top
+.line 10
+    LOAD_INT 1
+    LOAD_INT 5
+    ROT2
+    SUB
+    LOAD_INT 4
+    CMP_EQ_JUMP_IF_FALSE L_rot2
+
+; ROT3 moves the top value below the next two
+.line 19
+    LOAD_INT 1
+    LOAD_INT 2
+    LOAD_INT 3
+    ROT3
+    SUB
+    SUB
+    LOAD_INT 4
+    CMP_EQ_JUMP_IF_FALSE L_rot3
+
+; DUP_TOP2 copies the two values on top
+.line 30
+    LOAD_INT 3
+    LOAD_INT 4
+    DUP_TOP2
+    MUL
+    ADD
+    ADD
+    LOAD_INT 19
+    CMP_EQ_JUMP_IF_FALSE L_dup
+
+; JUMP_IF_FALSE_OR_POP keeps the value when it jumps
+.line 41
+    LOAD_BOOL false
+    JUMP_IF_FALSE_OR_POP L_and
+    LOAD_STR "not skipped"
+L_and:
+    LOAD_BOOL false
+    CMP_EQ_JUMP_IF_FALSE L_short
+
+; PEEK_JUMP_IF_EQUAL dispatches like a switch
+.line 50
+    LOAD_INT 2
+    LOAD_INT 1
+    PEEK_JUMP_IF_EQUAL L_one
+    LOAD_INT 2
+    PEEK_JUMP_IF_EQUAL L_two
+    JUMP L_switch
+L_one:
+    JUMP L_switch
+L_two:
+    POP_TOP
+
+; Locals and backward jumps, summing 0 to 99 in a function
+.line 62
+    LOAD_INT 100
+    LOAD_FUNCTION 0
+    CALL 1
+    LOAD_INT 4950
+    CMP_EQ_JUMP_IF_FALSE L_sum
+
+; Capture of a local cell by a closure
+.line 69
+    LOAD_INT 41
+    LOAD_FUNCTION 1
+    CALL 1
+    CALL 0
+    LOAD_INT 42
+    CMP_EQ_JUMP_IF_FALSE L_closure
+
+.line 77
+    LOAD_STR "All vm tests passed!"
+    LOAD_NAME "println"
+    CALL 1
+    POP_TOP
+    LOAD_NULL
+    RETURN
+
+L_rot2:
+    LOAD_STR "ROT2"
+    JUMP L_fail
+L_rot3:
+    LOAD_STR "ROT3"
+    JUMP L_fail
+L_dup:
+    LOAD_STR "DUP_TOP2"
+    JUMP L_fail
+L_short:
+    LOAD_STR "JUMP_IF_FALSE_OR_POP"
+    JUMP L_fail
+L_switch:
+    POP_TOP
+    LOAD_STR "PEEK_JUMP_IF_EQUAL"
+    JUMP L_fail
+L_sum:
+    LOAD_STR "loop"
+    JUMP L_fail
+L_closure:
+    LOAD_STR "closure"
+L_fail:
+    LOAD_NAME "throw"
+    CALL 1
+    RETURN
+
+.function 0 "sum" argc=1 locals=3
+.symbols "count" "total" "i"
+.line 110
+    INIT_LOCAL 0                             ; count
+    LOAD_INT 0
+    INIT_LOCAL 1                             ; total
+    LOAD_INT 0
+    INIT_LOCAL 2                             ; i
+L_loop:
+    LOAD_LOCAL 2                             ; i
+    LOAD_LOCAL 0                             ; count
+    CMP_LT_JUMP_IF_FALSE L_done
+    LOAD_LOCAL 1                             ; total
+    LOAD_LOCAL 2                             ; i
+    ADD
+    STORE_LOCAL 1                            ; total
+    INC_LOCAL 2                              ; i
+    JUMP L_loop
+L_done:
+    LOAD_LOCAL 1                             ; total
+    RETURN
+
+.function 1 "counter" argc=1 locals=1
+.symbols "start"
+.line 130
+    INIT_CELL 0                              ; start
+    LOAD_FUNCTION 2
+    RETURN
+
+.function 2 "next" argc=0 locals=0
+.capture local 0
+.line 136
+    LOAD_CAPTURE 0
+    LOAD_INT 1
+    ADD
+    RETURN
@@ -0,0 +1,385 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// opCodes maps the mnemonics back to their opcode.
+var opCodes = func() map[string]OpCode {
+	codes := map[string]OpCode{}
+	for op, name := range opNames {
+		codes[name] = op
+	}
+	return codes
+}()
+
+// asmFixup is a jump whose label was not known when it was encoded.
+type asmFixup struct {
+	address int
+	label   string
+	line    int
+}
+
+// assembler reads the text written by Disassemble, see Assemble.
+type assembler struct {
+	state     *AtomState
+	file      string
+	line      int
+	offset    int // Function table index of the first function
+	program   *AtomValue
+	functions map[int]*AtomValue
+	code      *AtomCode
+	labels    map[string]int
+	fixups    []asmFixup
+}
+
+// Assemble turns the text written by Disassemble into code. The
+// functions it declares are numbered from zero and appended to the
+// function table of state, LOAD_FUNCTION operands are moved with them.
+// Every function is checked by Verify before the program is returned.
+func Assemble(state *AtomState, file string, source string) (program *AtomValue, err error) {
+	a := &assembler{
+		state:     state,
+		file:      file,
+		offset:    state.FunctionTable.Len(),
+		functions: map[int]*AtomValue{},
+	}
+	for index, text := range strings.Split(source, "\n") {
+		a.line = index + 1
+		fields, err := asmFields(text)
+		if err != nil {
+			return nil, a.fail("%s", err.Error())
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if err := a.statement(fields); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.finish(); err != nil {
+		return nil, err
+	}
+	if a.program == nil {
+		return nil, a.fail("missing .program")
+	}
+
+	functions := make([]*AtomValue, len(a.functions))
+	for index := range functions {
+		function, ok := a.functions[index]
+		if !ok {
+			return nil, a.fail("function %d is not declared", index)
+		}
+		functions[index] = function
+	}
+	count := a.offset + len(functions)
+	for _, function := range append([]*AtomValue{a.program}, functions...) {
+		if err := Verify(function.Obj.(*AtomCode), count); err != nil {
+			return nil, err
+		}
+	}
+	for _, function := range functions {
+		state.SaveFunction(function)
+	}
+	return a.program, nil
+}
+
+func (a *assembler) fail(format string, args ...any) error {
+	return NewAtomAsmError(a.file, a.line, fmt.Sprintf(format, args...))
+}
+
+func (a *assembler) statement(fields []string) error {
+	head := fields[0]
+	switch {
+	case head == ".program" || head == ".function":
+		return a.function(fields)
+	case strings.HasSuffix(head, ":") && len(fields) == 1:
+		if err := a.started(head); err != nil {
+			return err
+		}
+		label := strings.TrimSuffix(head, ":")
+		if _, exists := a.labels[label]; exists {
+			return a.fail("label %s is already defined", label)
+		}
+		a.labels[label] = len(a.code.Code)
+		return nil
+	}
+	if err := a.started(head); err != nil {
+		return err
+	}
+
+	switch head {
+	case ".file":
+		if len(fields) != 2 {
+			return a.fail(".file expects a file name")
+		}
+		file, err := a.str(fields[1])
+		a.code.File = file
+		return err
+
+	case ".symbols":
+		for _, field := range fields[1:] {
+			symbol, err := a.str(field)
+			if err != nil {
+				return err
+			}
+			a.code.Symbols = append(a.code.Symbols, symbol)
+		}
+		return nil
+
+	case ".capture":
+		if len(fields) != 3 || (fields[1] != "local" && fields[1] != "outer") {
+			return a.fail(".capture expects local or outer and an index")
+		}
+		index, err := a.integer(fields[2])
+		a.code.Captures = append(a.code.Captures, AtomCapture{Local: fields[1] == "local", Index: index})
+		return err
+
+	case ".line":
+		if len(fields) != 2 {
+			return a.fail(".line expects a line number")
+		}
+		line, err := a.integer(fields[1])
+		a.code.Line = append(a.code.Line, AtomDebugLine{Line: line, Address: len(a.code.Code)})
+		return err
+	}
+	return a.instruction(fields)
+}
+
+// started fails when the statement comes before the first function.
+func (a *assembler) started(head string) error {
+	if a.code == nil {
+		return a.fail("%s outside of a function", head)
+	}
+	return nil
+}
+
+// function starts the code of .program "name" or .function index
+// "name", followed by argc=, locals=, async and global.
+func (a *assembler) function(fields []string) error {
+	if err := a.finish(); err != nil {
+		return err
+	}
+	head, fields := fields[0], fields[1:]
+
+	index := 0
+	if head == ".function" {
+		if len(fields) == 0 {
+			return a.fail(".function expects an index")
+		}
+		value, err := a.integer(fields[0])
+		if err != nil {
+			return err
+		}
+		if _, exists := a.functions[value]; exists {
+			return a.fail("function %d is already declared", value)
+		}
+		index, fields = value, fields[1:]
+	} else if a.program != nil {
+		return a.fail(".program is already declared")
+	}
+	if len(fields) == 0 {
+		return a.fail("%s expects a name", head)
+	}
+	name, err := a.str(fields[0])
+	if err != nil {
+		return err
+	}
+
+	code := NewAtomCode(a.file, name, false, 0)
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		var err error
+		switch key {
+		case "argc":
+			code.Argc, err = a.integer(value)
+		case "locals":
+			code.Locals, err = a.integer(value)
+		case "async":
+			code.Async = true
+		case "global":
+			code.Global = true
+		default:
+			err = a.fail("unknown attribute %s", field)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	function := NewAtomGenericValue(AtomTypeFunc, code)
+	if head == ".function" {
+		a.functions[index] = function
+	} else {
+		a.program = function
+	}
+	a.code = code
+	a.labels = map[string]int{}
+	a.fixups = nil
+	return nil
+}
+
+// finish resolves the jumps of the current function.
+func (a *assembler) finish() error {
+	if a.code == nil {
+		return nil
+	}
+	for _, fixup := range a.fixups {
+		target, ok := a.labels[fixup.label]
+		if !ok {
+			a.line = fixup.line
+			return a.fail("label %s is not defined", fixup.label)
+		}
+		writeOperand(a.code.Code, fixup.address, target)
+	}
+	return nil
+}
+
+// writeOperand encodes value in the 4 bytes at address.
+func writeOperand(code []OpCode, address int, value int) {
+	bytes := []byte{0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(bytes, uint32(value))
+	for i, b := range bytes {
+		code[address+i] = OpCode(b)
+	}
+}
+
+func (a *assembler) instruction(fields []string) error {
+	op, ok := opCodes[fields[0]]
+	if !ok {
+		return a.fail("unknown instruction %s", fields[0])
+	}
+	kinds := operandKinds(op)
+	written := 0
+	for _, kind := range kinds {
+		if kind != operandCache {
+			written++
+		}
+	}
+	if len(fields)-1 != written {
+		return a.fail("%s expects %d operands, got %d", fields[0], written, len(fields)-1)
+	}
+
+	code := a.code
+	code.Code = append(code.Code, op)
+	operands := fields[1:]
+	for _, kind := range kinds {
+		operand := 0
+		if kind != operandCache {
+			field := operands[0]
+			operands = operands[1:]
+			value, err := a.operand(kind, field)
+			if err != nil {
+				return err
+			}
+			operand = value
+		} else {
+			operand = code.AddCache()
+		}
+		code.Code = append(code.Code, 0, 0, 0, 0)
+		writeOperand(code.Code, len(code.Code)-4, operand)
+	}
+	return nil
+}
+
+// operand returns the encoded value of an operand, adding constants
+// to the pool of the current function.
+func (a *assembler) operand(kind operandKind, field string) (int, error) {
+	code := a.code
+	switch kind {
+	case operandInt, operandSlot:
+		return a.integer(field)
+
+	case operandFunc:
+		index, err := a.integer(field)
+		return a.offset + index, err
+
+	case operandBool:
+		switch field {
+		case "true":
+			return 1, nil
+		case "false":
+			return 0, nil
+		}
+		return 0, a.fail("expected true or false, got %s", field)
+
+	case operandNum:
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0, a.fail("invalid number %s", field)
+		}
+		return code.AddConstant(NewAtomValueNum(value)), nil
+
+	case operandBigInt:
+		value, ok := new(big.Int).SetString(field, 10)
+		if !ok {
+			return 0, a.fail("invalid big integer %s", field)
+		}
+		return code.AddConstant(NewAtomValueBigInt(value)), nil
+
+	case operandStr:
+		value, err := a.str(field)
+		if err != nil {
+			return 0, err
+		}
+		return code.AddConstant(a.state.Intern(value)), nil
+
+	case operandLabel:
+		if target, ok := a.labels[field]; ok {
+			return target, nil
+		}
+		a.fixups = append(a.fixups, asmFixup{address: len(code.Code), label: field, line: a.line})
+		return 0, nil
+	}
+	return 0, a.fail("unexpected operand %s", field)
+}
+
+func (a *assembler) integer(field string) (int, error) {
+	value, err := strconv.ParseInt(field, 10, 32)
+	if err != nil {
+		return 0, a.fail("invalid integer %s", field)
+	}
+	return int(value), nil
+}
+
+func (a *assembler) str(field string) (string, error) {
+	if !strings.HasPrefix(field, "\"") {
+		return "", a.fail("expected a quoted string, got %s", field)
+	}
+	value, err := strconv.Unquote(field)
+	if err != nil {
+		return "", a.fail("invalid string %s", field)
+	}
+	return value, nil
+}
+
+// asmFields splits a line on spaces, keeping quoted strings whole
+// and dropping the comment after ';'.
+func asmFields(text string) ([]string, error) {
+	fields := []string{}
+	for {
+		text = strings.TrimLeft(text, " \t\r")
+		if text == "" || text[0] == ';' {
+			return fields, nil
+		}
+		if text[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(text)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			fields = append(fields, quoted)
+			text = text[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(text, " \t\r;")
+		if end < 0 {
+			end = len(text)
+		}
+		fields = append(fields, text[:end])
+		text = text[end:]
+	}
+}
@@ -0,0 +1,372 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+var (
+	interpreterType = reflect.TypeOf((*AtomInterpreter)(nil))
+	frameType       = reflect.TypeOf((*AtomCallFrame)(nil))
+	valueType       = reflect.TypeOf((*AtomValue)(nil))
+	bigIntType      = reflect.TypeOf((*big.Int)(nil))
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+/*
+ * Signature of a Go function bound with Bind.
+ *
+ *	func(x float64) float64               sqrt(x)
+ *	func(path string, mode *int) error    open(path [, mode])
+ *	func(format string, args ...any)      printf(format, ...args)
+ *
+ * Leading *AtomInterpreter and *AtomCallFrame parameters receive the
+ * caller's, they are not visible to scripts.
+ */
+type atomSignature struct {
+	name     string
+	fn       reflect.Value
+	inject   []reflect.Type
+	params   []reflect.Type
+	required int
+	variadic bool
+}
+
+// Bind wraps a Go function as a native function value. Arguments are
+// validated against the parameter types and converted with Unmarshal
+// before the call, and results are converted back with Marshal.
+// Trailing pointers to scalars are optional and receive nil when
+// omitted. A variadic function accepts extra arguments. A non-nil
+// trailing error result becomes an Atom error.
+func Bind(name string, fn any) *AtomValue {
+	return NewAtomGenericValue(
+		AtomTypeNativeFunc,
+		BindFunc(name, fn),
+	)
+}
+
+func BindFunc(name string, fn any) *AtomNativeFunc {
+	signature := newAtomSignature(name, fn)
+	return NewNativeFunc(name, Variadict, signature.call)
+}
+
+func newAtomSignature(name string, fn any) *atomSignature {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		panic(fmt.Sprintf("bind %s: expected a function, got %T", name, fn))
+	}
+
+	fnType := value.Type()
+	signature := &atomSignature{
+		name:     name,
+		fn:       value,
+		inject:   []reflect.Type{},
+		params:   []reflect.Type{},
+		variadic: fnType.IsVariadic(),
+	}
+
+	index := 0
+	for ; index < fnType.NumIn(); index++ {
+		in := fnType.In(index)
+		if in != interpreterType && in != frameType {
+			break
+		}
+		signature.inject = append(signature.inject, in)
+	}
+	for ; index < fnType.NumIn(); index++ {
+		in := fnType.In(index)
+		if signature.variadic && index == fnType.NumIn()-1 {
+			in = in.Elem()
+		}
+		if !isBindable(in) {
+			panic(fmt.Sprintf("bind %s: unsupported parameter type %s", name, in))
+		}
+		signature.params = append(signature.params, in)
+	}
+
+	// Optional parameters are only allowed at the end
+	fixed := signature.fixed()
+	signature.required = fixed
+	for signature.required > 0 && isOptional(signature.params[signature.required-1]) {
+		signature.required--
+	}
+	for i := 0; i < signature.required; i++ {
+		if isOptional(signature.params[i]) {
+			panic(fmt.Sprintf("bind %s: optional parameter %d must be trailing", name, i+1))
+		}
+	}
+
+	if fnType.NumOut() > 2 || (fnType.NumOut() == 2 && fnType.Out(1) != errorType) {
+		panic(fmt.Sprintf("bind %s: expected at most one result and an error", name))
+	}
+
+	return signature
+}
+
+// fixed is the number of parameters before the variadic one.
+func (s *atomSignature) fixed() int {
+	if s.variadic {
+		return len(s.params) - 1
+	}
+	return len(s.params)
+}
+
+func (s *atomSignature) call(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
+	fixed := s.fixed()
+	if argc < s.required || (!s.variadic && argc > fixed) {
+		CleanupStack(frame, argc)
+		frame.Stack.Push(NewAtomValueError(
+			FormatError(frame, s.arityMessage(argc)),
+		))
+		return
+	}
+
+	args := []reflect.Value{}
+	for _, in := range s.inject {
+		if in == interpreterType {
+			args = append(args, reflect.ValueOf(interpreter))
+		} else {
+			args = append(args, reflect.ValueOf(frame))
+		}
+	}
+
+	for i := range argc {
+		paramType := s.params[min(i, len(s.params)-1)]
+		arg := frame.Stack.GetOffset(argc, i)
+		converted, message := s.argument(arg, paramType, i)
+		if message != "" {
+			CleanupStack(frame, argc)
+			frame.Stack.Push(NewAtomValueError(
+				FormatError(frame, message),
+			))
+			return
+		}
+		args = append(args, converted)
+	}
+
+	// Omitted optional parameters
+	for i := argc; i < fixed; i++ {
+		args = append(args, reflect.Zero(s.params[i]))
+	}
+
+	CleanupStack(frame, argc)
+
+	results := s.fn.Call(args)
+	if len(results) > 0 {
+		if last := results[len(results)-1]; last.Type() == errorType {
+			if !last.IsNil() {
+				frame.Stack.Push(NewAtomValueError(
+					FormatError(frame, s.errorMessage(last.Interface().(error))),
+				))
+				return
+			}
+			results = results[:len(results)-1]
+		}
+	}
+
+	if len(results) == 0 {
+		frame.Stack.Push(interpreter.State.NullValue)
+		return
+	}
+
+	result, nested, err := marshalSized(results[0].Interface())
+	if err != nil {
+		frame.Stack.Push(NewAtomValueError(
+			FormatError(frame, fmt.Sprintf("%s result: %s", s.name, err.Error())),
+		))
+		return
+	}
+	// The caller counts the result, what it holds is counted here
+	interpreter.allocate(nested)
+	frame.Stack.Push(result)
+}
+
+// argument converts the argument at index, scalars are checked here to
+// report the expected type, anything else goes through Unmarshal.
+func (s *atomSignature) argument(value *AtomValue, paramType reflect.Type, index int) (reflect.Value, string) {
+	if isScalarParam(paramType) {
+		converted, ok := fromAtomValue(value, paramType)
+		if !ok {
+			return converted, s.errorMessage(NewAtomArgumentError(index, bindTypeString(paramType), value))
+		}
+		return converted, ""
+	}
+
+	converted := reflect.New(paramType)
+	if err := Unmarshal(value, converted.Interface()); err != nil {
+		return converted, fmt.Sprintf("%s argument %d: %s", s.name, index+1, err.Error())
+	}
+	return converted.Elem(), ""
+}
+
+// AtomArgumentError is returned by a bound function that checks the
+// type of an *AtomValue parameter itself, Bind reports it like the
+// types it checks.
+type AtomArgumentError struct {
+	Index    int // Of the parameter visible to scripts, from 0
+	Expected string
+	Value    *AtomValue
+}
+
+func NewAtomArgumentError(index int, expected string, value *AtomValue) *AtomArgumentError {
+	return &AtomArgumentError{Index: index, Expected: expected, Value: value}
+}
+
+func (e *AtomArgumentError) Error() string {
+	return fmt.Sprintf("expects %s for argument %d, got %s", e.Expected, e.Index+1, GetTypeString(e.Value))
+}
+
+func (s *atomSignature) errorMessage(err error) string {
+	var argumentError *AtomArgumentError
+	if errors.As(err, &argumentError) {
+		return fmt.Sprintf("%s %s", s.name, argumentError.Error())
+	}
+	return fmt.Sprintf("%s: %s", s.name, err.Error())
+}
+
+func (s *atomSignature) arityMessage(argc int) string {
+	fixed := s.fixed()
+	switch {
+	case s.variadic:
+		return fmt.Sprintf("%s expects at least %d %s, got %d", s.name, s.required, pluralArguments(s.required), argc)
+	case s.required == fixed:
+		return fmt.Sprintf("%s expects %d %s, got %d", s.name, fixed, pluralArguments(fixed), argc)
+	default:
+		return fmt.Sprintf("%s expects %d to %d arguments, got %d", s.name, s.required, fixed, argc)
+	}
+}
+
+func pluralArguments(count int) string {
+	if count == 1 {
+		return "argument"
+	}
+	return "arguments"
+}
+
+func isScalarKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.String,
+		reflect.Bool:
+		return true
+	default:
+		return false
+	}
+}
+
+func isOptional(paramType reflect.Type) bool {
+	return paramType.Kind() == reflect.Pointer && isScalarKind(paramType.Elem().Kind())
+}
+
+// isScalarParam reports parameters converted by fromAtomValue.
+func isScalarParam(paramType reflect.Type) bool {
+	return paramType == valueType || paramType == bigIntType || isOptional(paramType) || isScalarKind(paramType.Kind())
+}
+
+func isBindable(paramType reflect.Type) bool {
+	switch paramType.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Interface:
+		return paramType.NumMethod() == 0
+	default:
+		return true
+	}
+}
+
+func bindTypeString(paramType reflect.Type) string {
+	if paramType == bigIntType {
+		return "big number"
+	}
+	if isOptional(paramType) {
+		paramType = paramType.Elem()
+	}
+	switch paramType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	default:
+		return strings.ToLower(paramType.String())
+	}
+}
+
+func fromAtomValue(value *AtomValue, paramType reflect.Type) (reflect.Value, bool) {
+	if paramType == valueType {
+		return reflect.ValueOf(value), true
+	}
+
+	if paramType == bigIntType {
+		if !CheckType(value, AtomTypeInt) && !CheckType(value, AtomTypeBigInt) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(CoerceToBigInt(value)), true
+	}
+
+	if isOptional(paramType) {
+		if CheckType(value, AtomTypeNull) {
+			return reflect.Zero(paramType), true
+		}
+		elem, ok := fromAtomValue(value, paramType.Elem())
+		if !ok {
+			return reflect.Value{}, false
+		}
+		pointer := reflect.New(paramType.Elem())
+		pointer.Elem().Set(elem)
+		return pointer, true
+	}
+
+	switch paramType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !IsNumberType(value) || (CheckType(value, AtomTypeNum) && !IsInteger(value.F64)) {
+			return reflect.Value{}, false
+		}
+		converted := reflect.New(paramType).Elem()
+		if converted.OverflowInt(CoerceToLong(value)) {
+			return reflect.Value{}, false
+		}
+		converted.SetInt(CoerceToLong(value))
+		return converted, true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !IsNumberType(value) || (CheckType(value, AtomTypeNum) && !IsInteger(value.F64)) || CoerceToLong(value) < 0 {
+			return reflect.Value{}, false
+		}
+		converted := reflect.New(paramType).Elem()
+		if converted.OverflowUint(uint64(CoerceToLong(value))) {
+			return reflect.Value{}, false
+		}
+		converted.SetUint(uint64(CoerceToLong(value)))
+		return converted, true
+
+	case reflect.Float32, reflect.Float64:
+		if !IsNumberType(value) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(CoerceToNum(value)).Convert(paramType), true
+
+	case reflect.String:
+		if !CheckType(value, AtomTypeStr) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(value.Str).Convert(paramType), true
+
+	case reflect.Bool:
+		if !CheckType(value, AtomTypeBool) {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(value.I32 == 1).Convert(paramType), true
+	}
+
+	return reflect.Value{}, false
+}
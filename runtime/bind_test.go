@@ -0,0 +1,137 @@
+package runtime
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// callBound calls fn bound as name with the arguments marshaled.
+func callBound(t *testing.T, name string, fn any, args ...any) (*AtomValue, error) {
+	t.Helper()
+	interpreter := NewInterpreter(NewAtomState())
+	values := make([]*AtomValue, len(args))
+	for i, arg := range args {
+		value, err := Marshal(arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values[i] = value
+	}
+	return interpreter.Call(Bind(name, fn), values...)
+}
+
+func expectBindError(t *testing.T, err error, message string) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), message) {
+		t.Fatalf("error %v, want %q", err, message)
+	}
+}
+
+func TestBindConvertsArgumentsAndResults(t *testing.T) {
+	result, err := callBound(t, "scale", func(values []float64, factor int) []float64 {
+		scaled := make([]float64, len(values))
+		for i, value := range values {
+			scaled[i] = value * float64(factor)
+		}
+		return scaled
+	}, []float64{1, 2.5}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scaled []float64
+	if err := Unmarshal(result, &scaled); err != nil {
+		t.Fatal(err)
+	}
+	if len(scaled) != 2 || scaled[0] != 2 || scaled[1] != 5 {
+		t.Fatalf("scaled = %v", scaled)
+	}
+}
+
+func TestBindArity(t *testing.T) {
+	repeat := func(text string, times *int) string {
+		if times == nil {
+			return text
+		}
+		return strings.Repeat(text, *times)
+	}
+
+	result, err := callBound(t, "repeat", repeat, "ab")
+	if err != nil || result.Str != "ab" {
+		t.Fatalf("omitted optional: %v %v", result, err)
+	}
+	result, err = callBound(t, "repeat", repeat, "ab", 3)
+	if err != nil || result.Str != "ababab" {
+		t.Fatalf("given optional: %v %v", result, err)
+	}
+	_, err = callBound(t, "repeat", repeat)
+	expectBindError(t, err, "repeat expects 1 to 2 arguments, got 0")
+	_, err = callBound(t, "repeat", repeat, "a", 1, 2)
+	expectBindError(t, err, "repeat expects 1 to 2 arguments, got 3")
+
+	_, err = callBound(t, "pair", func(a int, b int) int { return a + b }, 1)
+	expectBindError(t, err, "pair expects 2 arguments, got 1")
+
+	join := func(separator string, parts ...string) string {
+		return strings.Join(parts, separator)
+	}
+	result, err = callBound(t, "join", join, "-", "a", "b", "c")
+	if err != nil || result.Str != "a-b-c" {
+		t.Fatalf("variadic: %v %v", result, err)
+	}
+	_, err = callBound(t, "join", join)
+	expectBindError(t, err, "join expects at least 1 argument, got 0")
+}
+
+func TestBindArgumentTypes(t *testing.T) {
+	half := func(x int) int { return x / 2 }
+
+	_, err := callBound(t, "half", half, "four")
+	expectBindError(t, err, "half expects int for argument 1, got string")
+	_, err = callBound(t, "half", half, 2.5)
+	expectBindError(t, err, "half expects int for argument 1, got number")
+
+	_, err = callBound(t, "kind", func(value *AtomValue) (string, error) {
+		if !CheckType(value, AtomTypeArray) {
+			return "", NewAtomArgumentError(0, "array", value)
+		}
+		return "array", nil
+	}, true)
+	expectBindError(t, err, "kind expects array for argument 1, got bool")
+}
+
+func TestBindErrorsAndInjection(t *testing.T) {
+	_, err := callBound(t, "open", func(path string) error {
+		return errors.New("no such file")
+	}, "missing.txt")
+	expectBindError(t, err, "open: no such file")
+
+	result, err := callBound(t, "depth", func(interpreter *AtomInterpreter, frame *AtomCallFrame, offset int) int {
+		if interpreter == nil || frame == nil {
+			return -1
+		}
+		return frame.Depth + offset
+	}, 10)
+	if err != nil || result.I32 != 10 {
+		t.Fatalf("injected parameters: %v %v", result, err)
+	}
+}
+
+func TestBindRejectsSignatures(t *testing.T) {
+	signatures := map[string]any{
+		"not a function":        42,
+		"channel parameter":     func(chan int) {},
+		"optional not trailing": func(a *int, b int) {},
+		"two results":           func() (int, int) { return 0, 0 },
+	}
+	for name, fn := range signatures {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Bind should panic", name)
+				}
+			}()
+			Bind(name, fn)
+		}()
+	}
+}
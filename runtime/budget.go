@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrExecutionAborted is matched, with errors.Is, by the errors of
+	// runs stopped by a limit or a cancelled context.
+	ErrExecutionAborted = errors.New("execution aborted")
+	// ErrInstructionLimit is the cause of runs stopped by AtomLimits.Instructions.
+	ErrInstructionLimit = errors.New("instruction limit exceeded")
+)
+
+// AtomLimits bounds each run of an interpreter, zero values are unlimited.
+type AtomLimits struct {
+	// Instructions caps the number of executed instructions.
+	Instructions int64
+	// Timeout caps the wall-clock duration, it ends the run with
+	// context.DeadlineExceeded.
+	Timeout time.Duration
+	// Memory caps the approximate bytes allocated, see MemoryUsage.
+	Memory int64
+}
+
+// AtomAbortError ends a run that exceeded its budget, scripts cannot
+// catch it. It matches ErrExecutionAborted and its cause.
+type AtomAbortError struct {
+	Cause error
+}
+
+func NewAtomAbortError(cause error) *AtomAbortError {
+	return &AtomAbortError{
+		Cause: cause,
+	}
+}
+
+func (e *AtomAbortError) Error() string {
+	return ErrExecutionAborted.Error() + ": " + e.Cause.Error()
+}
+
+func (e *AtomAbortError) Unwrap() []error {
+	return []error{ErrExecutionAborted, e.Cause}
+}
+
+// Budget of the current run, limits are checked on backward jumps,
+// calls and sleeps so a running script always reaches a checkpoint.
+type atomBudget struct {
+	ctx      context.Context
+	executed int64
+	limit    int64
+}
+
+// begin starts the budget of a run, nested runs share the outer one.
+// The returned function ends it.
+func (i *AtomInterpreter) begin(ctx context.Context) func() {
+	if i.runs == 0 {
+		i.allocated = 0
+	}
+	i.runs++
+	if i.budget != nil || (ctx.Done() == nil && i.Limits.Instructions == 0 && i.Limits.Timeout == 0) {
+		return func() {
+			i.runs--
+		}
+	}
+
+	cancel := context.CancelFunc(func() {})
+	if i.Limits.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, i.Limits.Timeout)
+	}
+	i.budget = &atomBudget{
+		ctx:   ctx,
+		limit: i.Limits.Instructions,
+	}
+	return func() {
+		cancel()
+		i.budget = nil
+		i.runs--
+	}
+}
+
+// checkpoint aborts the run when its budget is exhausted.
+func (i *AtomInterpreter) checkpoint() {
+	budget := i.budget
+	if budget == nil {
+		return
+	}
+	if budget.limit > 0 && budget.executed > budget.limit {
+		panic(NewAtomAbortError(ErrInstructionLimit))
+	}
+	if err := budget.ctx.Err(); err != nil {
+		panic(NewAtomAbortError(err))
+	}
+}
+
+// sleep pauses the run, waking up early to abort it when
+// the budget ends first.
+func (i *AtomInterpreter) sleep(duration time.Duration) {
+	if i.budget == nil {
+		time.Sleep(duration)
+		return
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-i.budget.ctx.Done():
+	}
+	i.checkpoint()
+}
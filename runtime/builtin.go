@@ -17,6 +17,84 @@ func NewNativeFunc(name string, paramc int, callable func(interpreter *AtomInter
 }
 
 func DefineModule(interpreter *AtomInterpreter, name string, values map[string]*AtomValue) {
-	values["__name__"] = NewAtomValueStr(name)
-	interpreter.ModuleTable[name] = NewAtomGenericValue(AtomTypeObj, NewAtomObject(values))
+	if interpreter.State.Sandbox != nil && !interpreter.State.Sandbox.allowsModule(name) {
+		delete(interpreter.ModuleTable, name)
+		return
+	}
+	// Copy, the definition may be shared by other interpreters
+	elements := cloneDefinition(values)
+	if interpreter.State.Sandbox != nil {
+		interpreter.State.Sandbox.filterModule(name, elements)
+	}
+	elements["__name__"] = NewAtomValueStr(name)
+	interpreter.ModuleTable[name] = NewAtomGenericValue(AtomTypeObj, NewAtomObject(elements))
+}
+
+// cloneDefinition copies the values of a module definition deep enough
+// that nothing a script can mutate (objects, arrays, classes) is shared
+// with the other interpreters defining the same module. Functions are
+// immutable and stay shared.
+func cloneDefinition(values map[string]*AtomValue) map[string]*AtomValue {
+	copies := map[*AtomValue]*AtomValue{}
+	elements := make(map[string]*AtomValue, len(values))
+	for name, value := range values {
+		elements[name] = cloneValue(value, copies)
+	}
+	return elements
+}
+
+func cloneValue(value *AtomValue, copies map[*AtomValue]*AtomValue) *AtomValue {
+	if value == nil {
+		return nil
+	}
+	if clone, exists := copies[value]; exists {
+		return clone
+	}
+
+	switch value.Type {
+	case AtomTypeObj, AtomTypeEnum:
+		object, ok := value.Obj.(*AtomObject)
+		if !ok {
+			// Native handle, such as the engine of a Gin instance
+			return value
+		}
+		clone := NewAtomObject(make(map[string]*AtomValue, len(object.Elements)))
+		clone.Freeze = object.Freeze
+		copies[value] = NewAtomGenericValue(value.Type, clone)
+		for key, element := range object.Elements {
+			clone.Elements[key] = cloneValue(element, copies)
+		}
+
+	case AtomTypeArray:
+		array := value.Obj.(*AtomArray)
+		clone := NewAtomArray(make([]*AtomValue, len(array.Elements)))
+		clone.Freeze = array.Freeze
+		copies[value] = NewAtomGenericValue(value.Type, clone)
+		for index, element := range array.Elements {
+			clone.Elements[index] = cloneValue(element, copies)
+		}
+
+	case AtomTypeClass:
+		class := value.Obj.(*AtomClass)
+		clone := NewAtomClass(class.Name, nil, nil)
+		copies[value] = NewAtomGenericValue(value.Type, clone)
+		clone.Base = cloneValue(class.Base, copies)
+		clone.Proto = cloneValue(class.Proto, copies)
+
+	case AtomTypeClassInstance:
+		instance := value.Obj.(*AtomClassInstance)
+		clone := NewAtomClassInstance(nil)
+		copies[value] = NewAtomGenericValue(value.Type, clone)
+		clone.Prototype = cloneValue(instance.Prototype, copies)
+		clone.Shape = clone.Prototype.Obj.(*AtomClass).Shape
+		clone.Native = instance.Native
+		for slot, name := range instance.Shape.Names {
+			clone.Set(name, cloneValue(instance.Fields[slot], copies))
+		}
+
+	default:
+		// Scalars, functions and native functions are never mutated
+		return value
+	}
+	return copies[value]
 }
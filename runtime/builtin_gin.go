@@ -1,13 +1,15 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 func getGin(obj *AtomValue) *gin.Engine {
-	return obj.Obj.(*AtomClassInstance).Property.Obj.(*gin.Engine)
+	return obj.Obj.(*AtomClassInstance).Native.(*gin.Engine)
 }
 
 func getParams(c *gin.Context) *AtomValue {
@@ -23,7 +25,24 @@ func getBody(c *gin.Context) *AtomValue {
 	if err := c.BindJSON(&body); err != nil {
 		return NewAtomValueError(err.Error())
 	}
-	return ToAtomObject(body)
+	value, err := Marshal(body)
+	if err != nil {
+		return NewAtomValueError(err.Error())
+	}
+	return value
+}
+
+func respond(c *gin.Context, result *AtomValue) {
+	if CheckType(result, AtomTypeErr) {
+		c.JSON(getStatus(result), gin.H{"error": result.Str})
+		return
+	}
+	var body any
+	if err := Unmarshal(result, &body); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(getStatus(result), body)
 }
 
 func getStatus(obj *AtomValue) int {
@@ -74,9 +93,8 @@ func getStatus(obj *AtomValue) int {
 		}
 		return defaultStatus
 	case AtomTypeClassInstance:
-		classInstance := obj.Obj.(*AtomClassInstance)
-		if classInstance.Property != nil && classInstance.Property.Type == AtomTypeObj {
-			return getStatusFromObject(classInstance.Property.Obj.(*AtomObject))
+		if statusVal := obj.Obj.(*AtomClassInstance).Get("status"); statusVal != nil {
+			return getStatusFromValue(statusVal)
 		}
 		return defaultStatus
 	default:
@@ -84,548 +102,120 @@ func getStatus(obj *AtomValue) int {
 	}
 }
 
-func builtin_init_gin() *AtomValue {
-	var class = NewAtomGenericValue(
-		AtomTypeClass,
-		NewAtomClass("Gin", nil, NewAtomGenericValue(
-			AtomTypeObj,
-			NewAtomObject(map[string]*AtomValue{}),
-		)),
-	)
-
-	var protoType = class.Obj.(*AtomClass).Proto.Obj.(*AtomObject)
-
-	// func init() -> gin.Default();
-	var constructor = NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("init", Variadict, func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-			CleanupStack(frame, argc)
-			// Push this
-			this := NewAtomGenericValue(
-				AtomTypeClassInstance,
-				// prototype, property
-				NewAtomClassInstance(class, NewAtomGenericValue(AtomTypeObj, gin.New())),
-			)
-			frame.Stack.Push(this)
-		}),
-	)
-
-	// func get(path, callback) -> gin.GET
-	var get = NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("get", 3, func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-			if argc != 3 {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "get expects 2 arguments")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			this := frame.Stack.GetOffset(argc, 0)     // class instance
-			path := frame.Stack.GetOffset(argc, 1)     // path
-			callback := frame.Stack.GetOffset(argc, 2) // callback
-
-			if !CheckType(this, AtomTypeClassInstance) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "get expects a Gin instance")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(path, AtomTypeStr) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "get expects a path string")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(callback, AtomTypeFunc) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "get expects a callback user defined function")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			CleanupStack(frame, argc)
-
-			ginInstance := getGin(this)
-			ginInstance.GET(path.String(), func(c *gin.Context) {
-				// Create an object value for params
-				objValue := getParams(c)
-				// Push as argument
-				frame.Stack.Push(objValue)
-				// Call
-				DoCall(interpreter, frame, callback, 1)
-				result := frame.Stack.Pop()
-				// Response
-				c.JSON(getStatus(result), SerializeObject(result))
-			})
-
-			frame.Stack.Push(this)
-		}),
-	)
-
-	// func post(path, callback) -> gin.POST
-	var post = NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("post", 3, func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-			if argc != 3 {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "post expects 2 arguments")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			this := frame.Stack.GetOffset(argc, 0)     // class instance
-			path := frame.Stack.GetOffset(argc, 1)     // path
-			callback := frame.Stack.GetOffset(argc, 2) // callback
-
-			if !CheckType(this, AtomTypeClassInstance) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "post expects a Gin instance")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(path, AtomTypeStr) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "post expects a path string")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(callback, AtomTypeFunc) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "post expects a callback user defined function")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			CleanupStack(frame, argc)
-
-			ginInstance := getGin(this)
-			ginInstance.POST(path.String(), func(c *gin.Context) {
-				// Create an object value for params
-				objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-					"body":   getBody(c),
-					"params": getParams(c),
-				}))
-				// Push as argument
-				frame.Stack.Push(objValue)
-				// Call
-				DoCall(interpreter, frame, callback, 1)
-				result := frame.Stack.Pop()
-				// Response
-				c.JSON(getStatus(result), SerializeObject(result))
-			})
-
-			frame.Stack.Push(this)
-		}),
-	)
-
-	// func put(path, callback) -> gin.PUT
-	var put = NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("put", 3, func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-			if argc != 3 {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "put expects 2 arguments")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			this := frame.Stack.GetOffset(argc, 0)     // class instance
-			path := frame.Stack.GetOffset(argc, 1)     // path
-			callback := frame.Stack.GetOffset(argc, 2) // callback
-
-			if !CheckType(this, AtomTypeClassInstance) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "put expects a Gin instance")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(path, AtomTypeStr) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "put expects a path string")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(callback, AtomTypeFunc) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "put expects a callback user defined function")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			CleanupStack(frame, argc)
-
-			ginInstance := getGin(this)
-			ginInstance.PUT(path.String(), func(c *gin.Context) {
-				// Create an object value for params
-				objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-					"body":   getBody(c),
-					"params": getParams(c),
-				}))
-				// Push as argument
-				frame.Stack.Push(objValue)
-				// Call
-				DoCall(interpreter, frame, callback, 1)
-				result := frame.Stack.Pop()
-				// Response
-				c.JSON(getStatus(result), SerializeObject(result))
-			})
-
-			frame.Stack.Push(this)
-		}),
-	)
-
-	// func patch(path, callback) -> gin.PATCH
-	var patch = NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("patch", 3, func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-			if argc != 3 {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "patch expects 2 arguments")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			this := frame.Stack.GetOffset(argc, 0)     // class instance
-			path := frame.Stack.GetOffset(argc, 1)     // path
-			callback := frame.Stack.GetOffset(argc, 2) // callback
-
-			if !CheckType(this, AtomTypeClassInstance) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "patch expects a Gin instance")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(path, AtomTypeStr) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "patch expects a path string")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(callback, AtomTypeFunc) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "patch expects a callback user defined function")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
+// gin_init is the constructor of Gin, it pushes its own instance
+// holding the engine.
+func gin_init(this *AtomValue) (*AtomValue, error) {
+	if !CheckType(this, AtomTypeClassInstance) {
+		return nil, errors.New("Gin must be created with new")
+	}
+	// The class of this, each interpreter has its own copy
+	instance := NewAtomClassInstance(this.Obj.(*AtomClassInstance).Prototype)
+	instance.Native = gin.New()
+	return NewAtomGenericValue(AtomTypeClassInstance, instance), nil
+}
 
-			CleanupStack(frame, argc)
+// gin_route returns the route method registering callback for method
+// requests. The callback receives the params, or the body and the
+// params when withBody is set.
+func gin_route(method string, withBody bool) func(*AtomInterpreter, *AtomCallFrame, *AtomValue, string, *AtomValue) (*AtomValue, error) {
+	return func(interpreter *AtomInterpreter, frame *AtomCallFrame, this *AtomValue, path string, callback *AtomValue) (*AtomValue, error) {
+		if !CheckType(this, AtomTypeClassInstance) {
+			return nil, NewAtomArgumentError(0, "Gin instance", this)
+		}
+		if !CheckType(callback, AtomTypeFunc) {
+			return nil, NewAtomArgumentError(2, "function", callback)
+		}
 
-			ginInstance := getGin(this)
-			ginInstance.PATCH(path.String(), func(c *gin.Context) {
-				// Create an object value for params
-				objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
+		getGin(this).Handle(method, path, func(c *gin.Context) {
+			// Create an object value for params
+			objValue := getParams(c)
+			if withBody {
+				objValue = NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
 					"body":   getBody(c),
-					"params": getParams(c),
+					"params": objValue,
 				}))
-				// Push as argument
-				frame.Stack.Push(objValue)
-				// Call
-				DoCall(interpreter, frame, callback, 1)
-				result := frame.Stack.Pop()
-				// Response
-				c.JSON(getStatus(result), SerializeObject(result))
-			})
-
-			frame.Stack.Push(this)
-		}),
-	)
-
-	// func delete(path, callback) -> gin.DELETE
-	var delete = NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("delete", 3, func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-			if argc != 3 {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "delete expects 2 arguments")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
 			}
+			// Push as argument
+			frame.Stack.Push(objValue)
+			// Call
+			DoCall(interpreter, frame, callback, 1)
+			result := frame.Stack.Pop()
+			// Response
+			respond(c, result)
+		})
 
-			this := frame.Stack.GetOffset(argc, 0)     // class instance
-			path := frame.Stack.GetOffset(argc, 1)     // path
-			callback := frame.Stack.GetOffset(argc, 2) // callback
-
-			if !CheckType(this, AtomTypeClassInstance) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "delete expects a Gin instance")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(path, AtomTypeStr) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "delete expects a path string")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !CheckType(callback, AtomTypeFunc) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "delete expects a callback user defined function")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			CleanupStack(frame, argc)
-
-			ginInstance := getGin(this)
-			ginInstance.DELETE(path.String(), func(c *gin.Context) {
-				// Create an object value for params
-				objValue := getParams(c)
-				// Push as argument
-				frame.Stack.Push(objValue)
-				// Call
-				DoCall(interpreter, frame, callback, 1)
-				result := frame.Stack.Pop()
-				// Response
-				c.JSON(getStatus(result), SerializeObject(result))
-			})
-
-			frame.Stack.Push(this)
-		}),
-	)
-
-	// func serve() -> gin.Run();
-	var serve = NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("serve", 2, func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-			if argc != 2 {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "serve expects 0 arguments")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			this := frame.Stack.GetOffset(argc, 0) // class instance
-			port := frame.Stack.GetOffset(argc, 1) // port number
-
-			if !CheckType(this, AtomTypeClassInstance) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "serve expects a Gin instance")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			if !IsNumberType(port) {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "serve expects a port number")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			// Check if valid port range
-			portNum := CoerceToInt(port)
-			if portNum < 1 || portNum > 65535 {
-				CleanupStack(frame, argc)
-				message := FormatError(frame, "serve expects a valid port number")
-				frame.Stack.Push(NewAtomValueError(message))
-				return
-			}
-
-			gin := getGin(this)
-			gin.Run(fmt.Sprintf(":%d", portNum))
-			frame.Stack.Push(interpreter.State.NullValue)
-		}),
-	)
-
-	protoType.Set("init", constructor)
-	protoType.Set("get", get)
-	protoType.Set("post", post)
-	protoType.Set("put", put)
-	protoType.Set("patch", patch)
-	protoType.Set("delete", delete)
-	protoType.Set("serve", serve)
-
-	return class
-}
-
-func gin_created(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("created expected 1 argument, got %d", argc))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
+		return this, nil
 	}
-
-	data := frame.Stack.Pop()
-	objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-		"status": NewAtomValueInt(201),
-		"data":   data,
-	}))
-
-	frame.Stack.Push(objValue)
 }
 
-func gin_ok(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("ok expected 1 argument, got %d", argc))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
+// func serve(port) -> gin.Run();
+func gin_serve(this *AtomValue, port *AtomValue) error {
+	if !CheckType(this, AtomTypeClassInstance) {
+		return NewAtomArgumentError(0, "Gin instance", this)
 	}
-
-	data := frame.Stack.Pop()
-	objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-		"status": NewAtomValueInt(200),
-		"data":   data,
-	}))
-
-	frame.Stack.Push(objValue)
-}
-
-func gin_badRequest(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("badRequest expected 1 argument, got %d", argc))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
+	if !IsNumberType(port) {
+		return NewAtomArgumentError(1, "port number", port)
 	}
-
-	data := frame.Stack.Pop()
-	objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-		"status": NewAtomValueInt(400),
-		"data":   data,
-	}))
-
-	frame.Stack.Push(objValue)
-}
-
-func gin_unauthorized(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("unauthorized expected 1 argument, got %d", argc))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
+	// Check if valid port range
+	portNum := CoerceToInt(port)
+	if portNum < 1 || portNum > 65535 {
+		return errors.New("expects a valid port number")
 	}
-
-	data := frame.Stack.Pop()
-	objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-		"status": NewAtomValueInt(401),
-		"data":   data,
-	}))
-
-	frame.Stack.Push(objValue)
+	return getGin(this).Run(fmt.Sprintf(":%d", portNum))
 }
 
-func gin_forbidden(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("forbidden expected 1 argument, got %d", argc))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
-	}
-
-	data := frame.Stack.Pop()
-	objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-		"status": NewAtomValueInt(403),
-		"data":   data,
-	}))
-
-	frame.Stack.Push(objValue)
-}
+func builtin_init_gin() *AtomValue {
+	var class = NewAtomGenericValue(
+		AtomTypeClass,
+		NewAtomClass("Gin", nil, NewAtomGenericValue(
+			AtomTypeObj,
+			NewAtomObject(map[string]*AtomValue{}),
+		)),
+	)
 
-func gin_notFound(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("notFound expected 1 argument, got %d", argc))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
-	}
+	var protoType = class.Obj.(*AtomClass).Proto.Obj.(*AtomObject)
 
-	data := frame.Stack.Pop()
-	objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-		"status": NewAtomValueInt(404),
-		"data":   data,
-	}))
+	protoType.Set("init", Bind("init", gin_init))
+	protoType.Set("get", Bind("get", gin_route(http.MethodGet, false)))
+	protoType.Set("post", Bind("post", gin_route(http.MethodPost, true)))
+	protoType.Set("put", Bind("put", gin_route(http.MethodPut, true)))
+	protoType.Set("patch", Bind("patch", gin_route(http.MethodPatch, true)))
+	protoType.Set("delete", Bind("delete", gin_route(http.MethodDelete, false)))
+	protoType.Set("serve", Bind("serve", gin_serve))
 
-	frame.Stack.Push(objValue)
+	return class
 }
 
-func gin_internalServerError(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("internalServerError expected 1 argument, got %d", argc))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
+// gin_status returns the helper wrapping data in a response with status.
+func gin_status(status int) func(data *AtomValue) *AtomValue {
+	return func(data *AtomValue) *AtomValue {
+		return newGinResponse(status, data)
 	}
-
-	data := frame.Stack.Pop()
-	objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-		"status": NewAtomValueInt(500),
-		"data":   data,
-	}))
-
-	frame.Stack.Push(objValue)
 }
 
-func gin_response(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 2 {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("internalServerError expected 1 argument, got %d", argc))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
-	}
-
-	status := frame.Stack.GetOffset(argc, 0)
-	data := frame.Stack.GetOffset(argc, 1)
-
+func gin_response(status *AtomValue, data *AtomValue) (*AtomValue, error) {
 	if !IsNumberType(status) {
-		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("status code must be a type of int, got %s", GetTypeString(status)))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
+		return nil, NewAtomArgumentError(0, "status code", status)
 	}
+	return newGinResponse(int(CoerceToInt(status)), data), nil
+}
 
-	objValue := NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
-		"status": NewAtomValueInt(int(CoerceToInt(status))),
+func newGinResponse(status int, data *AtomValue) *AtomValue {
+	return NewAtomGenericValue(AtomTypeObj, NewAtomObject(map[string]*AtomValue{
+		"status": NewAtomValueInt(status),
 		"data":   data,
 	}))
-
-	frame.Stack.Push(objValue)
 }
 
 var EXPORT_GIN = map[string]*AtomValue{
-	"Gin": builtin_init_gin(),
-	"created": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("created", 1, gin_created),
-	),
-	"ok": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("ok", 1, gin_ok),
-	),
-	"badRequest": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("badRequest", 1, gin_badRequest),
-	),
-	"unauthorized": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("unauthorized", 1, gin_unauthorized),
-	),
-	"forbidden": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("forbidden", 1, gin_forbidden),
-	),
-	"notFound": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("notFound", 1, gin_notFound),
-	),
-	"internalServerError": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("internalServerError", 1, gin_internalServerError),
-	),
+	"Gin":                 builtin_init_gin(),
+	"created":             Bind("created", gin_status(http.StatusCreated)),
+	"ok":                  Bind("ok", gin_status(http.StatusOK)),
+	"badRequest":          Bind("badRequest", gin_status(http.StatusBadRequest)),
+	"unauthorized":        Bind("unauthorized", gin_status(http.StatusUnauthorized)),
+	"forbidden":           Bind("forbidden", gin_status(http.StatusForbidden)),
+	"notFound":            Bind("notFound", gin_status(http.StatusNotFound)),
+	"internalServerError": Bind("internalServerError", gin_status(http.StatusInternalServerError)),
 	// Generic
-	"response": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("response", 2, gin_response),
-	),
+	"response": Bind("response", gin_response),
 }
@@ -1,188 +1,55 @@
 package runtime
 
-import "strconv"
-
-func number_parseInt(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: parseInt expects 1 argument"),
-		))
-		return
-	}
-
-	value := frame.Stack.Pop()
-
-	if !CheckType(value, AtomTypeStr) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: parseInt expects a string"),
-		))
-		return
-	}
-
-	str := value.Str
+import (
+	"errors"
+	"strconv"
+)
 
+func number_parseInt(str string) (int, error) {
 	intValue, err := strconv.Atoi(str)
 	if err != nil {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: parseInt expects a valid integer"),
-		))
-		return
+		return 0, errors.New("expects a valid integer")
 	}
-	frame.Stack.Push(NewAtomValueInt(intValue))
+	return intValue, nil
 }
 
-func number_parseFloat(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: parseFloat expects 1 argument"),
-		))
-		return
-	}
-
-	value := frame.Stack.Pop()
-
-	if !CheckType(value, AtomTypeStr) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: parseFloat expects a string"),
-		))
-		return
-	}
-
-	str := value.Str
-
+func number_parseFloat(str string) (float64, error) {
 	floatValue, err := strconv.ParseFloat(str, 64)
 	if err != nil {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: parseFloat expects a valid float"),
-		))
-		return
+		return 0, errors.New("expects a valid float")
 	}
-	frame.Stack.Push(NewAtomValueNum(floatValue))
+	return floatValue, nil
 }
 
-func number_toString(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: toString expects 1 argument"),
-		))
-		return
-	}
-
-	value := frame.Stack.Pop()
-
-	if !CheckType(value, AtomTypeInt) && !CheckType(value, AtomTypeNum) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: toString expects a number"),
-		))
-		return
-	}
-
-	num := CoerceToNum(value)
-	frame.Stack.Push(NewAtomValueStr(strconv.FormatFloat(num, 'g', -1, 64)))
+func number_toString(num float64) string {
+	return strconv.FormatFloat(num, 'g', -1, 64)
 }
 
 // cast to int
-func number_int(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: int expects 1 argument"),
-		))
-		return
-	}
-
-	value := frame.Stack.Pop()
-
+func number_int(value *AtomValue) (*AtomValue, error) {
 	if !IsNumberType(value) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: int expects a number"),
-		))
-		return
+		return nil, NewAtomArgumentError(0, "number", value)
 	}
-
-	num := CoerceToInt(value)
-	frame.Stack.Push(NewAtomValueInt(int(num)))
+	return NewAtomValueInt(int(CoerceToInt(value))), nil
 }
 
 // cast to num
-func number_num(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: num expects 1 argument"),
-		))
-		return
-	}
-
-	value := frame.Stack.Pop()
-
-	if !IsNumberType(value) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: num expects a number"),
-		))
-		return
-	}
-
-	num := CoerceToNum(value)
-	frame.Stack.Push(NewAtomValueNum(float64(num)))
+func number_num(num float64) float64 {
+	return num
 }
 
-func number_bigInt(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: bigInt expects 1 argument"),
-		))
-		return
-	}
-
-	value := frame.Stack.Pop()
-
+func number_bigInt(value *AtomValue) (*AtomValue, error) {
 	if !IsNumberType(value) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "Error: bigInt expects a number"),
-		))
-		return
+		return nil, NewAtomArgumentError(0, "number", value)
 	}
-
-	num := CoerceToBigInt(value)
-	frame.Stack.Push(NewAtomValueBigInt(num))
+	return NewAtomValueBigInt(CoerceToBigInt(value)), nil
 }
 
 var EXPORT_NUMBER = map[string]*AtomValue{
-	"parseInt": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("number.parseInt", 1, number_parseInt),
-	),
-	"parseFloat": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("number.parseFloat", 1, number_parseFloat),
-	),
-	"toString": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("number.toString", 1, number_toString),
-	),
-	"int": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("number.int", 1, number_int),
-	),
-	"num": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("number.num", 1, number_num),
-	),
-	"bigInt": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("number.bigInt", 1, number_bigInt),
-	),
+	"parseInt":   Bind("number.parseInt", number_parseInt),
+	"parseFloat": Bind("number.parseFloat", number_parseFloat),
+	"toString":   Bind("number.toString", number_toString),
+	"int":        Bind("number.int", number_int),
+	"num":        Bind("number.num", number_num),
+	"bigInt":     Bind("number.bigInt", number_bigInt),
 }
@@ -1,93 +1,40 @@
 package runtime
 
-func obj_freeze(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "freeze expects 1 argument"),
-		))
-		return
-	}
-
-	obj := frame.Stack.Pop()
-
+func obj_freeze(obj *AtomValue) (*AtomValue, error) {
 	if CheckType(obj, AtomTypeObj) {
 		obj.Obj.(*AtomObject).Freeze = true
 	} else if CheckType(obj, AtomTypeArray) {
 		obj.Obj.(*AtomArray).Freeze = true
 	} else {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "cannot freeze non-object"),
-		))
-		return
+		return nil, NewAtomArgumentError(0, "object or array", obj)
 	}
-	frame.Stack.Push(obj)
+	return obj, nil
 }
 
-func obj_keys(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "keys expects 1 argument"),
-		))
-		return
+func obj_keys(obj *AtomValue) ([]string, error) {
+	if !CheckType(obj, AtomTypeObj) {
+		return nil, NewAtomArgumentError(0, "object", obj)
 	}
-
-	obj := frame.Stack.Pop()
-
-	if CheckType(obj, AtomTypeObj) {
-		keys := []*AtomValue{}
-		for key := range obj.Obj.(*AtomObject).Elements {
-			keys = append(keys, NewAtomValueStr(key))
-		}
-		frame.Stack.Push(NewAtomGenericValue(
-			AtomTypeArray,
-			NewAtomArray(keys),
-		))
-	} else {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(FormatError(frame, "cannot keys non-object")))
+	keys := []string{}
+	for key := range obj.Obj.(*AtomObject).Elements {
+		keys = append(keys, key)
 	}
+	return keys, nil
 }
 
-func obj_values(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "values expects 1 argument"),
-		))
-		return
+func obj_values(obj *AtomValue) ([]*AtomValue, error) {
+	if !CheckType(obj, AtomTypeObj) {
+		return nil, NewAtomArgumentError(0, "object", obj)
 	}
-
-	obj := frame.Stack.Pop()
-
-	if CheckType(obj, AtomTypeObj) {
-		values := []*AtomValue{}
-		for _, value := range obj.Obj.(*AtomObject).Elements {
-			values = append(values, value)
-		}
-		frame.Stack.Push(NewAtomGenericValue(
-			AtomTypeArray,
-			NewAtomArray(values),
-		))
-	} else {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(FormatError(frame, "cannot values non-object")))
+	values := []*AtomValue{}
+	for _, value := range obj.Obj.(*AtomObject).Elements {
+		values = append(values, value)
 	}
+	return values, nil
 }
 
 var EXPORT_OBJECT = map[string]*AtomValue{
-	"freeze": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("freeze", 1, obj_freeze),
-	),
-	"keys": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("keys", 1, obj_keys),
-	),
-	"values": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("values", 1, obj_values),
-	),
+	"freeze": Bind("freeze", obj_freeze),
+	"keys":   Bind("keys", obj_keys),
+	"values": Bind("values", obj_values),
 }
@@ -0,0 +1,19 @@
+package runtime
+
+func runtime_memory(interpreter *AtomInterpreter) int64 {
+	return interpreter.MemoryUsage()
+}
+
+func runtime_memoryLimit(interpreter *AtomInterpreter) int64 {
+	return interpreter.Limits.Memory
+}
+
+func runtime_callDepth(frame *AtomCallFrame) int {
+	return frame.Depth
+}
+
+var EXPORT_RUNTIME = map[string]*AtomValue{
+	"memory":      Bind("memory", runtime_memory),
+	"memoryLimit": Bind("memoryLimit", runtime_memoryLimit),
+	"callDepth":   Bind("callDepth", runtime_callDepth),
+}
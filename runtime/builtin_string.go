@@ -5,323 +5,62 @@ import (
 	"strings"
 )
 
-func string_len(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		message := fmt.Sprintf("string.len expected 1 argument, got %d", argc)
-		frame.Stack.Push(NewAtomValueError(message))
-		return
+// string_len takes the value itself, its rune offsets are cached
+func string_len(value *AtomValue) (float64, error) {
+	if !CheckType(value, AtomTypeStr) {
+		return 0, NewAtomArgumentError(0, "string", value)
 	}
-
-	arg := frame.Stack.Pop()
-
-	if !CheckType(arg, AtomTypeStr) {
-		CleanupStack(frame, argc-1)
-		message := fmt.Sprintf("string.len expected a string, got %s", GetTypeString(arg))
-		frame.Stack.Push(NewAtomValueError(message))
-		return
-	}
-
-	runes := []rune(arg.Str)
-
-	frame.Stack.Push(NewAtomValueNum(float64(len(runes))))
-}
-
-func string_toUpper(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.toUpper expected 1 argument"),
-		))
-		return
-	}
-
-	arg := frame.Stack.Pop()
-
-	if !CheckType(arg, AtomTypeStr) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.toUpper expected a string"),
-		))
-		return
-	}
-
-	str := arg.Str
-	frame.Stack.Push(NewAtomValueStr(strings.ToUpper(str)))
-}
-
-func string_toLower(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.toLower expected 1 argument"),
-		))
-		return
-	}
-
-	arg := frame.Stack.Pop()
-
-	if !CheckType(arg, AtomTypeStr) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.toLower expected a string"),
-		))
-		return
-	}
-
-	str := arg.Str
-	frame.Stack.Push(NewAtomValueStr(strings.ToLower(str)))
+	return float64(StrLen(value)), nil
 }
 
-func string_contains(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 2 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.contains expected 2 arguments"),
-		))
-		return
-	}
-
-	arg0 := frame.Stack.GetOffset(argc, 0)
-	arg1 := frame.Stack.GetOffset(argc, 1)
-
-	if !CheckType(arg0, AtomTypeStr) {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.contains expected a string"),
-		))
-		return
-	}
-
-	if !CheckType(arg1, AtomTypeStr) {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.contains expected a string"),
-		))
-		return
-	}
-
-	CleanupStack(frame, argc)
-
-	str0 := arg0.Str
-	str1 := arg1.Str
-
-	if strings.Contains(str0, str1) {
-		frame.Stack.Push(interpreter.State.TrueValue)
-	} else {
-		frame.Stack.Push(interpreter.State.FalseValue)
-	}
-}
-
-func string_reverse(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.reverse expected 1 argument"),
-		))
-		return
-	}
-
-	arg := frame.Stack.Pop()
-
-	if !CheckType(arg, AtomTypeStr) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.reverse expected a string"),
-		))
-		return
-	}
-
-	str := arg.Str
+func string_reverse(str string) string {
 	// Use a more efficient string reversal with runes to handle UTF-8 correctly
 	runes := []rune(str)
 	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
 		runes[i], runes[j] = runes[j], runes[i]
 	}
-	frame.Stack.Push(NewAtomValueStr(string(runes)))
+	return string(runes)
 }
 
-func string_runes(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.rune expected 1 argument"),
-		))
-		return
-	}
-
-	arg := frame.Stack.Pop()
-
-	if !CheckType(arg, AtomTypeStr) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.rune expected a string"),
-		))
-		return
-	}
-
-	runes := []rune(arg.Str)
-	elements := make([]*AtomValue, len(runes))
+func string_runes(str string) []int {
+	runes := []rune(str)
+	elements := make([]int, len(runes))
 	for i, r := range runes {
-		elements[i] = NewAtomValueInt(int(r))
+		elements[i] = int(r)
 	}
-	frame.Stack.Push(NewAtomGenericValue(
-		AtomTypeArray,
-		NewAtomArray(elements),
-	))
+	return elements
 }
 
-func string_bytes(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.bytes expected 1 argument"),
-		))
-		return
+func string_bytes(str string) []int {
+	elements := make([]int, len(str))
+	for i := range len(str) {
+		elements[i] = int(str[i])
 	}
-
-	arg := frame.Stack.Pop()
-
-	if !CheckType(arg, AtomTypeStr) {
-		CleanupStack(frame, argc-1)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.bytes expected a string"),
-		))
-		return
-	}
-
-	bytes := []byte(arg.Str)
-	elements := make([]*AtomValue, len(bytes))
-	for i, b := range bytes {
-		elements[i] = NewAtomValueInt(int(b))
-	}
-	frame.Stack.Push(NewAtomGenericValue(
-		AtomTypeArray,
-		NewAtomArray(elements),
-	))
+	return elements
 }
 
-func string_format(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc < 2 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.format expected at least 2 arguments"),
-		))
-		return
-	}
-
-	format := frame.Stack.GetOffset(argc, 0)
-
-	if !CheckType(format, AtomTypeStr) {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.format expected a string"),
-		))
-		return
-	}
-
-	formatStr := format.Str
-	count := strings.Count(formatStr, "{}")
-	if count != argc-1 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, fmt.Sprintf("string.format expected %d arguments, got %d", count, argc-1)),
-		))
-		return
+func string_format(format string, args ...*AtomValue) (string, error) {
+	count := strings.Count(format, "{}")
+	if count != len(args) {
+		return "", fmt.Errorf("expected %d arguments, got %d", count, len(args))
 	}
 
 	// Build the result string directly without creating an intermediate array
-	result := formatStr
-	for i := 1; i < argc; i++ {
-		arg := frame.Stack.GetOffset(argc, i)
+	result := format
+	for _, arg := range args {
 		result = strings.Replace(result, "{}", arg.String(), 1)
 	}
-
-	CleanupStack(frame, argc)
-	frame.Stack.Push(NewAtomValueStr(result))
-}
-
-func string_split(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
-	if argc != 2 {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.split expected 2 arguments"),
-		))
-		return
-	}
-
-	arg0 := frame.Stack.GetOffset(argc, 0)
-	arg1 := frame.Stack.GetOffset(argc, 1)
-
-	if !CheckType(arg0, AtomTypeStr) {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.split expected a string"),
-		))
-		return
-	}
-
-	if !CheckType(arg1, AtomTypeStr) {
-		CleanupStack(frame, argc)
-		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "string.split expected a string"),
-		))
-		return
-	}
-
-	CleanupStack(frame, argc)
-
-	str0 := arg0.Str
-	str1 := arg1.Str
-
-	splits := strings.Split(str0, str1)
-
-	items := make([]*AtomValue, len(splits))
-	for i, split := range splits {
-		items[i] = NewAtomValueStr(split)
-	}
-
-	frame.Stack.Push(NewAtomGenericValue(
-		AtomTypeArray,
-		NewAtomArray(items),
-	))
+	return result, nil
 }
 
 var EXPORT_STRING = map[string]*AtomValue{
-	"len": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.len", 1, string_len),
-	),
-	"toUpper": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.toUpper", 1, string_toUpper),
-	),
-	"toLower": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.toLower", 1, string_toLower),
-	),
-	"contains": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.contains", 2, string_contains),
-	),
-	"reverse": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.reverse", 1, string_reverse),
-	),
-	"runes": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.rune", 1, string_runes),
-	),
-	"bytes": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.bytes", 1, string_bytes),
-	),
-	"format": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.format", Variadict, string_format),
-	),
-	"split": NewAtomGenericValue(
-		AtomTypeNativeFunc,
-		NewNativeFunc("string.split", 2, string_split),
-	),
+	"len":      Bind("string.len", string_len),
+	"toUpper":  Bind("string.toUpper", strings.ToUpper),
+	"toLower":  Bind("string.toLower", strings.ToLower),
+	"contains": Bind("string.contains", strings.Contains),
+	"reverse":  Bind("string.reverse", string_reverse),
+	"runes":    Bind("string.runes", string_runes),
+	"bytes":    Bind("string.bytes", string_bytes),
+	"format":   Bind("string.format", string_format),
+	"split":    Bind("string.split", strings.Split),
 }
@@ -0,0 +1,21 @@
+package runtime
+
+// AtomCell holds a local variable captured by a closure, the frame
+// declaring the variable and its closures share the cell.
+type AtomCell struct {
+	Value *AtomValue
+}
+
+func NewAtomCell(value *AtomValue) *AtomCell {
+	return &AtomCell{
+		Value: value,
+	}
+}
+
+// AtomCapture tells where a closure finds a cell when it is created,
+// in a local slot of the creating frame or among the cells captured by
+// the creating function itself.
+type AtomCapture struct {
+	Local bool
+	Index int
+}
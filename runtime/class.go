@@ -4,6 +4,7 @@ type AtomClass struct {
 	Name  string
 	Base  *AtomValue // AtomClass
 	Proto *AtomValue // AtomObject
+	Shape *AtomShape // Shape of new instances
 }
 
 func NewAtomClass(name string, base, proto *AtomValue) *AtomClass {
@@ -11,5 +12,6 @@ func NewAtomClass(name string, base, proto *AtomValue) *AtomClass {
 		Name:  name,
 		Base:  base,
 		Proto: proto,
+		Shape: NewAtomShape(),
 	}
 }
@@ -2,7 +2,9 @@ package runtime
 
 type AtomClassInstance struct {
 	Prototype *AtomValue // AtomClass
-	Property  *AtomValue // AtomObject
+	Shape     *AtomShape
+	Fields    []*AtomValue // Indexed by the slots of Shape
+	Native    any          // Host object of builtin classes
 }
 
 type AtomMethod struct {
@@ -17,11 +19,42 @@ type AtomNativeMethod struct {
 	Callable func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int)
 }
 
-func NewAtomClassInstance(prototype, property *AtomValue) *AtomClassInstance {
-	return &AtomClassInstance{
+func NewAtomClassInstance(prototype *AtomValue) *AtomClassInstance {
+	instance := &AtomClassInstance{
 		Prototype: prototype,
-		Property:  property,
+		Fields:    []*AtomValue{},
 	}
+	if prototype != nil {
+		instance.Shape = prototype.Obj.(*AtomClass).Shape
+	}
+	return instance
+}
+
+// Get returns the field name, nil when the instance has none.
+func (i *AtomClassInstance) Get(name string) *AtomValue {
+	if slot := i.Shape.Slot(name); slot >= 0 {
+		return i.Fields[slot]
+	}
+	return nil
+}
+
+// Set stores the field name, adding it moves the instance to the next shape.
+func (i *AtomClassInstance) Set(name string, value *AtomValue) {
+	if slot := i.Shape.Slot(name); slot >= 0 {
+		i.Fields[slot] = value
+		return
+	}
+	i.Shape = i.Shape.With(name)
+	i.Fields = append(i.Fields, value)
+}
+
+// Properties returns the fields of the instance by name.
+func (i *AtomClassInstance) Properties() map[string]*AtomValue {
+	properties := make(map[string]*AtomValue, len(i.Fields))
+	for slot, name := range i.Shape.Names {
+		properties[name] = i.Fields[slot]
+	}
+	return properties
 }
 
 func NewAtomMethod(this *AtomValue, fn *AtomValue) *AtomMethod {
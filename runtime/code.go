@@ -1,24 +1,67 @@
 package runtime
 
+import (
+	"math"
+	"math/big"
+)
+
 type AtomCode struct {
-	File    string
-	Name    string
-	Async   bool
-	Argc    int
-	Line    []AtomDebugLine
-	Code    []OpCode // Instructions
-	Capture *AtomEnv
+	File      string
+	Name      string
+	Async     bool
+	Argc      int
+	Line      []AtomDebugLine
+	Code      []OpCode          // Instructions
+	Constants []*AtomValue      // Strings, names and numbers used by the instructions
+	Caches    []AtomInlineCache // Inline caches of the attribute instructions
+	Global    bool              // Declares globals, its frames get their own AtomEnv
+	Locals    int               // Number of local slots
+	Symbols   []string          // Names of the local slots
+	Captures  []AtomCapture     // Cells captured by the closures of this code
+	Capture   *AtomEnv
+	Cells     []*AtomCell // Captured cells, initialized at runtime
+	tier      *atomTier
+	constants map[atomConstant]int // Index of each constant, see AddConstant
+}
+
+// atomConstant identifies a constant by its type and value.
+type atomConstant struct {
+	Type  AtomType
+	bits  uint64     // Numbers
+	text  string     // Strings and big numbers
+	value *AtomValue // Anything else, by identity
+}
+
+func constantKey(value *AtomValue) atomConstant {
+	switch value.Type {
+	case AtomTypeStr:
+		return atomConstant{Type: value.Type, text: value.Str}
+	case AtomTypeNum:
+		return atomConstant{Type: value.Type, bits: math.Float64bits(value.F64)}
+	case AtomTypeBigInt:
+		return atomConstant{Type: value.Type, text: value.Obj.(*big.Int).String()}
+	default:
+		return atomConstant{Type: value.Type, value: value}
+	}
 }
 
 func NewAtomCode(file, name string, async bool, argc int) *AtomCode {
 	return &AtomCode{
-		File:    file,
-		Name:    name,
-		Async:   async,
-		Argc:    argc,
-		Line:    []AtomDebugLine{},
-		Code:    []OpCode{},
-		Capture: nil, // initialized at runtime
+		File:      file,
+		Name:      name,
+		Async:     async,
+		Argc:      argc,
+		Line:      []AtomDebugLine{},
+		Code:      []OpCode{},
+		Constants: []*AtomValue{},
+		Caches:    []AtomInlineCache{},
+		Global:    false,
+		Locals:    0,
+		Symbols:   []string{},
+		Captures:  []AtomCapture{},
+		Capture:   nil, // initialized at runtime
+		Cells:     nil, // initialized at runtime
+		tier:      &atomTier{},
 	}
 }
 
@@ -56,5 +99,39 @@ func (c *AtomCode) HashValue() int {
 		hash = hash*31 + uint32(opcode)
 	}
 
+	// Hash the constants
+	for _, constant := range c.Constants {
+		hash = hash*31 + uint32(constant.Type)
+		for _, b := range []byte(constant.String()) {
+			hash = hash*31 + uint32(b)
+		}
+	}
+
 	return int(hash)
 }
+
+// AddConstant returns the index of value in the constant pool,
+// equal constants share one entry.
+func (c *AtomCode) AddConstant(value *AtomValue) int {
+	if c.constants == nil {
+		c.constants = make(map[atomConstant]int, len(c.Constants))
+		for index, constant := range c.Constants {
+			if _, exists := c.constants[constantKey(constant)]; !exists {
+				c.constants[constantKey(constant)] = index
+			}
+		}
+	}
+	key := constantKey(value)
+	if index, exists := c.constants[key]; exists {
+		return index
+	}
+	c.Constants = append(c.Constants, value)
+	c.constants[key] = len(c.Constants) - 1
+	return len(c.Constants) - 1
+}
+
+// AddCache returns the index of a new inline cache.
+func (c *AtomCode) AddCache() int {
+	c.Caches = append(c.Caches, AtomInlineCache{Slot: -1})
+	return len(c.Caches) - 1
+}
@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestAddConstantSharesEqualValues(t *testing.T) {
+	code := NewAtomCode("<test>", "<test>", false, 0)
+	str := code.AddConstant(NewAtomValueStr("name"))
+	num := code.AddConstant(NewAtomValueNum(1.5))
+	big := code.AddConstant(NewAtomValueBigInt(BigInt("12345678901234567890")))
+	zero := code.AddConstant(NewAtomValueNum(0))
+
+	if code.AddConstant(NewAtomValueStr("name")) != str ||
+		code.AddConstant(NewAtomValueNum(1.5)) != num ||
+		code.AddConstant(NewAtomValueBigInt(BigInt("12345678901234567890"))) != big {
+		t.Fatal("equal constants should share one entry")
+	}
+	// -0 and 0 are different numbers, a string is not a number
+	if code.AddConstant(NewAtomValueNum(math.Copysign(0, -1))) == zero || code.AddConstant(NewAtomValueStr("1.5")) == num {
+		t.Fatal("different constants should not share an entry")
+	}
+	if len(code.Constants) != 6 {
+		t.Fatalf("%d constants, want 6", len(code.Constants))
+	}
+}
+
+func BenchmarkAddConstant(b *testing.B) {
+	names := make([]*AtomValue, 20000)
+	for i := range names {
+		names[i] = NewAtomValueStr(fmt.Sprintf("name%d", i))
+	}
+	for b.Loop() {
+		code := NewAtomCode("<benchmark>", "<benchmark>", false, 0)
+		for _, name := range names {
+			code.AddConstant(name)
+		}
+	}
+}
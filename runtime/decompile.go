@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -30,14 +31,19 @@ func Decompile(code *AtomCode) string {
 			pc += 4
 
 		case OpLoadNum:
-			value := ReadNum(code.Code, pc)
-			builder.WriteString(fmt.Sprintf("LOAD_NUM %f\n", value))
-			pc += 8
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("LOAD_NUM %d (%s)\n", index, constantText(code, index)))
+			pc += 4
+
+		case OpLoadBigInt:
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("LOAD_BIGINT %d (%s)\n", index, constantText(code, index)))
+			pc += 4
 
 		case OpLoadStr:
-			value := ReadStr(code.Code, pc)
-			builder.WriteString(fmt.Sprintf("LOAD_STR \"%s\"\n", value))
-			pc += len(value) + 1
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("LOAD_STR %d (%s)\n", index, constantText(code, index)))
+			pc += 4
 
 		case OpLoadBool:
 			value := ReadInt(code.Code, pc)
@@ -58,14 +64,34 @@ func Decompile(code *AtomCode) string {
 			pc += 4
 
 		case OpLoadName:
-			index := ReadStr(code.Code, pc)
-			builder.WriteString(fmt.Sprintf("LOAD_NAME %s\n", index))
-			pc += len(index) + 1
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("LOAD_NAME %d (%s)\n", index, constantText(code, index)))
+			pc += 4
+
+		case OpLoadLocal:
+			slot := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("LOAD_LOCAL %d (%s)\n", slot, slotName(code, slot)))
+			pc += 4
+
+		case OpLoadCell:
+			slot := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("LOAD_CELL %d (%s)\n", slot, slotName(code, slot)))
+			pc += 4
+
+		case OpLoadCapture:
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("LOAD_CAPTURE %d\n", index))
+			pc += 4
 
 		case OpLoadModule:
-			name := ReadStr(code.Code, pc)
-			builder.WriteString(fmt.Sprintf("LOAD_MODULE \"%s\"\n", name))
-			pc += len(name) + 1
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("LOAD_MODULE %d (%s)\n", index, constantText(code, index)))
+			pc += 4
+
+		case OpImportModule:
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("IMPORT_MODULE %d (%s)\n", index, constantText(code, index)))
+			pc += 4
 
 		case OpLoadFunction:
 			offset := ReadInt(code.Code, pc)
@@ -74,9 +100,9 @@ func Decompile(code *AtomCode) string {
 
 		case OpMakeClass:
 			size := ReadInt(code.Code, pc)
-			name := ReadStr(code.Code, pc+4)
-			builder.WriteString(fmt.Sprintf("MAKE_CLASS %d \"%s\"\n", size, name))
-			pc += 4 + len(name) + 1
+			index := ReadInt(code.Code, pc+4)
+			builder.WriteString(fmt.Sprintf("MAKE_CLASS %d %d (%s)\n", size, index, constantText(code, index)))
+			pc += 8
 
 		case OpExtendClass:
 			builder.WriteString("EXTEND_CLASS\n")
@@ -96,6 +122,18 @@ func Decompile(code *AtomCode) string {
 			builder.WriteString(fmt.Sprintf("CALL %d\n", argc))
 			pc += 4
 
+		case OpTailCall:
+			argc := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("TAIL_CALL %d\n", argc))
+			pc += 4
+
+		case OpCallAttribute:
+			index := ReadInt(code.Code, pc)
+			cache := ReadInt(code.Code, pc+4)
+			argc := ReadInt(code.Code, pc+8)
+			builder.WriteString(fmt.Sprintf("CALL_ATTRIBUTE %d (%s) %d %d\n", index, constantText(code, index), cache, argc))
+			pc += 12
+
 		case OpAwait:
 			builder.WriteString("AWAIT\n")
 
@@ -121,9 +159,15 @@ func Decompile(code *AtomCode) string {
 			builder.WriteString("INDEX\n")
 
 		case OpPluckAttribute:
-			attr := ReadStr(code.Code, pc)
-			builder.WriteString(fmt.Sprintf("PLUCK_ATTRIBUTE \"%s\"\n", attr))
-			pc += len(attr) + 1
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("PLUCK_ATTRIBUTE %d (%s)\n", index, constantText(code, index)))
+			pc += 4
+
+		case OpLoadAttribute:
+			index := ReadInt(code.Code, pc)
+			cache := ReadInt(code.Code, pc+4)
+			builder.WriteString(fmt.Sprintf("LOAD_ATTRIBUTE %d (%s) %d\n", index, constantText(code, index), cache))
+			pc += 8
 
 		case OpMul:
 			builder.WriteString("MUL\n")
@@ -174,23 +218,64 @@ func Decompile(code *AtomCode) string {
 			builder.WriteString("XOR\n")
 
 		case OpStoreModule:
-			name := ReadStr(code.Code, pc)
-			builder.WriteString(fmt.Sprintf("STORE_MODULE \"%s\"\n", name))
-			pc += len(name) + 1
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("STORE_MODULE %d (%s)\n", index, constantText(code, index)))
+			pc += 4
+
+		case OpInitName:
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("INIT_NAME %d (%s)\n", index, constantText(code, index)))
+			pc += 4
+
+		case OpStoreName:
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("STORE_NAME %d (%s)\n", index, constantText(code, index)))
+			pc += 4
 
 		case OpInitLocal:
-			index := ReadStr(code.Code, pc)
-			builder.WriteString(fmt.Sprintf("INIT_LOCAL %s\n", index))
-			pc += len(index) + 1
+			slot := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("INIT_LOCAL %d (%s)\n", slot, slotName(code, slot)))
+			pc += 4
 
 		case OpStoreLocal:
-			index := ReadStr(code.Code, pc)
-			builder.WriteString(fmt.Sprintf("STORE_LOCAL %s\n", index))
-			pc += len(index) + 1
+			slot := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("STORE_LOCAL %d (%s)\n", slot, slotName(code, slot)))
+			pc += 4
+
+		case OpInitCell:
+			slot := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("INIT_CELL %d (%s)\n", slot, slotName(code, slot)))
+			pc += 4
+
+		case OpStoreCell:
+			slot := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("STORE_CELL %d (%s)\n", slot, slotName(code, slot)))
+			pc += 4
+
+		case OpStoreCapture:
+			index := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("STORE_CAPTURE %d\n", index))
+			pc += 4
+
+		case OpIncLocal:
+			slot := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("INC_LOCAL %d (%s)\n", slot, slotName(code, slot)))
+			pc += 4
+
+		case OpDecLocal:
+			slot := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("DEC_LOCAL %d (%s)\n", slot, slotName(code, slot)))
+			pc += 4
 
 		case OpSetIndex:
 			builder.WriteString("SET_INDEX\n")
 
+		case OpStoreAttribute:
+			index := ReadInt(code.Code, pc)
+			cache := ReadInt(code.Code, pc+4)
+			builder.WriteString(fmt.Sprintf("STORE_ATTRIBUTE %d (%s) %d\n", index, constantText(code, index), cache))
+			pc += 8
+
 		case OpJumpIfFalseOrPop:
 			offset := ReadInt(code.Code, pc)
 			builder.WriteString(fmt.Sprintf("JUMP_IF_FALSE_OR_POP %d\n", offset))
@@ -231,6 +316,36 @@ func Decompile(code *AtomCode) string {
 			builder.WriteString(fmt.Sprintf("ABSOLUTE_JUMP %d\n", offset))
 			pc += 4
 
+		case OpCmpLtJumpIfFalse:
+			offset := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("CMP_LT_JUMP_IF_FALSE %d\n", offset))
+			pc += 4
+
+		case OpCmpLteJumpIfFalse:
+			offset := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("CMP_LTE_JUMP_IF_FALSE %d\n", offset))
+			pc += 4
+
+		case OpCmpGtJumpIfFalse:
+			offset := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("CMP_GT_JUMP_IF_FALSE %d\n", offset))
+			pc += 4
+
+		case OpCmpGteJumpIfFalse:
+			offset := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("CMP_GTE_JUMP_IF_FALSE %d\n", offset))
+			pc += 4
+
+		case OpCmpEqJumpIfFalse:
+			offset := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("CMP_EQ_JUMP_IF_FALSE %d\n", offset))
+			pc += 4
+
+		case OpCmpNeJumpIfFalse:
+			offset := ReadInt(code.Code, pc)
+			builder.WriteString(fmt.Sprintf("CMP_NE_JUMP_IF_FALSE %d\n", offset))
+			pc += 4
+
 		case OpDupTop:
 			builder.WriteString("DUP_TOP\n")
 
@@ -240,16 +355,6 @@ func Decompile(code *AtomCode) string {
 		case OpPopTop:
 			builder.WriteString("POP_TOP\n")
 
-		case OpEnterBlock:
-			depth := ReadInt(code.Code, pc)
-			pc += 4
-			builder.WriteString(fmt.Sprintf("ENTER_BLOCK (depth = %d)\n", depth))
-
-		case OpExitBlock:
-			depth := ReadInt(code.Code, pc)
-			pc += 4
-			builder.WriteString(fmt.Sprintf("EXIT_BLOCK (depth = %d)\n", depth))
-
 		case OpRot2:
 			builder.WriteString("ROT2\n")
 
@@ -269,3 +374,21 @@ func Decompile(code *AtomCode) string {
 
 	return strings.TrimSpace(builder.String())
 }
+
+func slotName(code *AtomCode, slot int) string {
+	if slot >= 0 && slot < len(code.Symbols) {
+		return code.Symbols[slot]
+	}
+	return "?"
+}
+
+func constantText(code *AtomCode, index int) string {
+	if index < 0 || index >= len(code.Constants) {
+		return "?"
+	}
+	constant := code.Constants[index]
+	if constant.Type == AtomTypeStr {
+		return strconv.Quote(constant.Str)
+	}
+	return constant.String()
+}
@@ -0,0 +1,188 @@
+package runtime
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// operandKind tells how an operand is written in assembly.
+type operandKind int
+
+const (
+	operandInt    operandKind = iota // Counts, sizes and capture indices
+	operandSlot                      // Local slot, named in a comment
+	operandBool                      // true or false
+	operandNum                       // Number constant
+	operandBigInt                    // Big integer constant
+	operandStr                       // Quoted string constant
+	operandCache                     // Inline cache, not written
+	operandLabel                     // Jump target
+	operandFunc                      // Function table index
+)
+
+// operandKinds returns the operands of op in the order they are
+// encoded, each of them takes 4 bytes.
+func operandKinds(op OpCode) []operandKind {
+	switch op {
+	case OpMakeModule, OpLoadInt, OpLoadArray, OpLoadObject, OpMakeEnum,
+		OpCallConstructor, OpCall, OpTailCall, OpLoadCapture, OpStoreCapture:
+		return []operandKind{operandInt}
+	case OpLoadLocal, OpLoadCell, OpInitLocal, OpStoreLocal, OpInitCell, OpStoreCell,
+		OpIncLocal, OpDecLocal:
+		return []operandKind{operandSlot}
+	case OpLoadBool:
+		return []operandKind{operandBool}
+	case OpLoadNum:
+		return []operandKind{operandNum}
+	case OpLoadBigInt:
+		return []operandKind{operandBigInt}
+	case OpLoadStr, OpLoadName, OpLoadModule, OpImportModule, OpPluckAttribute,
+		OpStoreModule, OpInitName, OpStoreName:
+		return []operandKind{operandStr}
+	case OpLoadFunction:
+		return []operandKind{operandFunc}
+	case OpMakeClass:
+		return []operandKind{operandInt, operandStr}
+	case OpLoadAttribute, OpStoreAttribute:
+		return []operandKind{operandStr, operandCache}
+	case OpCallAttribute:
+		return []operandKind{operandStr, operandCache, operandInt}
+	}
+	if IsJump(op) {
+		return []operandKind{operandLabel}
+	}
+	return nil
+}
+
+// Disassemble writes program and the functions of the table as text
+// Assemble reads back. Constants are written in place of their index,
+// jump targets as labels and the source line of the instructions
+// with .line directives.
+func Disassemble(program *AtomCode, functions *AtomStack) string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("; atom disassembly of %s\n", program.File))
+
+	builder.WriteString("\n.program")
+	disassembleCode(&builder, program)
+	for index := range functions.Len() {
+		builder.WriteString(fmt.Sprintf("\n.function %d", index))
+		disassembleCode(&builder, functions.Get(index).Obj.(*AtomCode))
+	}
+	return builder.String()
+}
+
+func disassembleCode(builder *strings.Builder, code *AtomCode) {
+	builder.WriteString(fmt.Sprintf(" %s argc=%d locals=%d", strconv.Quote(code.Name), code.Argc, code.Locals))
+	if code.Async {
+		builder.WriteString(" async")
+	}
+	if code.Global {
+		builder.WriteString(" global")
+	}
+	builder.WriteString(fmt.Sprintf("\n.file %s\n", strconv.Quote(code.File)))
+	if len(code.Symbols) > 0 {
+		builder.WriteString(".symbols")
+		for _, symbol := range code.Symbols {
+			builder.WriteString(" " + strconv.Quote(symbol))
+		}
+		builder.WriteString("\n")
+	}
+	for _, capture := range code.Captures {
+		source := "outer"
+		if capture.Local {
+			source = "local"
+		}
+		builder.WriteString(fmt.Sprintf(".capture %s %d\n", source, capture.Index))
+	}
+
+	// Number the jump targets in address order
+	targets := []int{}
+	labels := map[int]string{}
+	for pc := 0; pc < len(code.Code); pc += 1 + OperandSize(code.Code[pc]) {
+		if IsJump(code.Code[pc]) {
+			target := ReadInt(code.Code, pc+1)
+			if _, ok := labels[target]; !ok {
+				labels[target] = ""
+				targets = append(targets, target)
+			}
+		}
+	}
+	sort.Ints(targets)
+	for index, target := range targets {
+		labels[target] = fmt.Sprintf("L%d", index+1)
+	}
+
+	line, next := -1, 0
+	for pc := 0; pc < len(code.Code); pc += 1 + OperandSize(code.Code[pc]) {
+		if label, ok := labels[pc]; ok {
+			builder.WriteString(label + ":\n")
+		}
+		// The line of an instruction is the last entry at or before it
+		current := line
+		for next < len(code.Line) && code.Line[next].Address <= pc {
+			current = code.Line[next].Line
+			next++
+		}
+		if current != line {
+			line = current
+			builder.WriteString(fmt.Sprintf(".line %d\n", line))
+		}
+
+		if op := code.Code[pc]; pc+1+OperandSize(op) > len(code.Code) {
+			builder.WriteString(fmt.Sprintf("    ; %s truncated\n", OpName(op)))
+			break
+		}
+		text, comment := disassembleInstruction(code, pc, labels)
+		if comment != "" {
+			text = fmt.Sprintf("%-40s ; %s", text, comment)
+		}
+		builder.WriteString("    " + text + "\n")
+	}
+	if label, ok := labels[len(code.Code)]; ok {
+		builder.WriteString(label + ":\n")
+	}
+}
+
+// disassembleInstruction returns the text of the instruction at pc
+// and a comment naming its local slot.
+func disassembleInstruction(code *AtomCode, pc int, labels map[int]string) (text string, comment string) {
+	op := code.Code[pc]
+	parts := []string{OpName(op)}
+	for index, kind := range operandKinds(op) {
+		operand := ReadInt(code.Code, pc+1+4*index)
+		switch kind {
+		case operandInt, operandFunc:
+			parts = append(parts, strconv.Itoa(int(int32(operand))))
+		case operandSlot:
+			parts = append(parts, strconv.Itoa(operand))
+			comment = slotName(code, operand)
+		case operandBool:
+			parts = append(parts, strconv.FormatBool(operand != 0))
+		case operandNum, operandBigInt, operandStr:
+			parts = append(parts, constantLiteral(code, operand))
+		case operandLabel:
+			parts = append(parts, labels[operand])
+		}
+	}
+	return strings.Join(parts, " "), comment
+}
+
+// constantLiteral writes a constant the way Assemble parses it.
+func constantLiteral(code *AtomCode, index int) string {
+	if index < 0 || index >= len(code.Constants) {
+		return "?"
+	}
+	constant := code.Constants[index]
+	switch constant.Type {
+	case AtomTypeStr:
+		return strconv.Quote(constant.Str)
+	case AtomTypeNum:
+		return strconv.FormatFloat(constant.F64, 'g', -1, 64)
+	case AtomTypeBigInt:
+		return constant.Obj.(*big.Int).Text(10)
+	}
+	return constant.String()
+}
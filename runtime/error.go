@@ -0,0 +1,82 @@
+package runtime
+
+import "fmt"
+
+// AtomRuntimeError is raised when a script throws an error that is
+// not caught, the message carries the formatted stack trace.
+type AtomRuntimeError struct {
+	Message string
+}
+
+func NewAtomRuntimeError(message string) *AtomRuntimeError {
+	return &AtomRuntimeError{
+		Message: message,
+	}
+}
+
+func (e *AtomRuntimeError) Error() string {
+	return e.Message
+}
+
+// AtomMarshalError is returned when a value cannot be converted
+// between Go and Atom, the path locates it inside the converted value.
+type AtomMarshalError struct {
+	Path    string
+	Message string
+}
+
+func NewAtomMarshalError(path string, message string) *AtomMarshalError {
+	return &AtomMarshalError{
+		Path:    path,
+		Message: message,
+	}
+}
+
+func (e *AtomMarshalError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// AtomVerifyError is returned by Verify, the address is the offset of
+// the rejected instruction in the code of the function.
+type AtomVerifyError struct {
+	Function string
+	File     string
+	Address  int
+	Message  string
+}
+
+func NewAtomVerifyError(function string, file string, address int, message string) *AtomVerifyError {
+	return &AtomVerifyError{
+		Function: function,
+		File:     file,
+		Address:  address,
+		Message:  message,
+	}
+}
+
+func (e *AtomVerifyError) Error() string {
+	return fmt.Sprintf("%s in %s at %d: %s", e.File, e.Function, e.Address, e.Message)
+}
+
+// AtomAsmError is returned by Assemble, the line is the one of the
+// assembly text that was rejected.
+type AtomAsmError struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func NewAtomAsmError(file string, line int, message string) *AtomAsmError {
+	return &AtomAsmError{
+		File:    file,
+		Line:    line,
+		Message: message,
+	}
+}
+
+func (e *AtomAsmError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
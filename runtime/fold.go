@@ -0,0 +1,80 @@
+package runtime
+
+// Fold applies the unary or binary operator op to constant operands
+// with the same Do* operation the interpreter runs, so folded results
+// match the runtime exactly. It returns nil for opcodes that are not
+// operators and when the operation fails, leaving the error to the
+// runtime.
+func Fold(state *AtomState, op OpCode, operands ...*AtomValue) *AtomValue {
+	code := NewAtomCode("<fold>", "<fold>", false, 0)
+	code.Line = append(code.Line, AtomDebugLine{Line: 0, Address: 0})
+	frame := NewAtomCallFrame(nil, NewAtomGenericValue(AtomTypeFunc, code), 0)
+	interpreter := &AtomInterpreter{State: state}
+
+	switch len(operands) {
+	case 1:
+		val := operands[0]
+		switch op {
+		case OpBitNot:
+			DoBitNot(interpreter, frame, val)
+		case OpNot:
+			DoNot(interpreter, frame, val)
+		case OpNeg:
+			DoNeg(frame, val)
+		case OpPos:
+			DoPos(frame, val)
+		case OpTypeof:
+			DoTypeof(frame, val)
+		default:
+			return nil
+		}
+
+	case 2:
+		val0, val1 := operands[0], operands[1]
+		switch op {
+		case OpMul:
+			DoMultiplication(frame, val0, val1)
+		case OpDiv:
+			DoDivision(frame, val0, val1)
+		case OpMod:
+			DoModulus(frame, val0, val1)
+		case OpAdd:
+			DoAddition(frame, val0, val1)
+		case OpSub:
+			DoSubtraction(frame, val0, val1)
+		case OpShl:
+			DoShiftLeft(frame, val0, val1)
+		case OpShr:
+			DoShiftRight(frame, val0, val1)
+		case OpCmpLt:
+			DoCmpLt(interpreter, frame, val0, val1)
+		case OpCmpLte:
+			DoCmpLte(interpreter, frame, val0, val1)
+		case OpCmpGt:
+			DoCmpGt(interpreter, frame, val0, val1)
+		case OpCmpGte:
+			DoCmpGte(interpreter, frame, val0, val1)
+		case OpCmpEq:
+			DoCmpEq(interpreter, frame, val0, val1)
+		case OpCmpNe:
+			DoCmpNe(interpreter, frame, val0, val1)
+		case OpAnd:
+			DoAnd(frame, val0, val1)
+		case OpOr:
+			DoOr(frame, val0, val1)
+		case OpXor:
+			DoXor(frame, val0, val1)
+		default:
+			return nil
+		}
+
+	default:
+		return nil
+	}
+
+	result := frame.Stack.Pop()
+	if CheckType(result, AtomTypeErr) {
+		return nil
+	}
+	return result
+}
@@ -5,18 +5,38 @@ type AtomCallFrame struct {
 	Fn      *AtomValue     // Function
 	Ip      int            // Instruction pointer
 	Env     *AtomEnv       // Environment
+	Locals  []*AtomValue   // Local slots
+	Cells   []*AtomCell    // Local slots captured by closures
 	Stack   *AtomStack     // EvaluationStack
 	Promise *AtomValue     // Promise
 	State   ExecutionState // For async/await
+	Depth   int            // Number of callers
+	// Instance is set on the frame of an initializer, its caller
+	// receives the instance instead of the returned value
+	Instance *AtomValue
 }
 
 func NewAtomCallFrame(caller *AtomCallFrame, fn *AtomValue, ip int) *AtomCallFrame {
+	depth := 0
+	if caller != nil {
+		depth = caller.Depth + 1
+	}
+	// Functions only read and write globals in their environment,
+	// declaring them is left to global code
+	code := fn.Obj.(*AtomCode)
+	env := code.Capture
+	if env == nil || code.Global {
+		env = NewAtomEnv(code.Capture)
+	}
 	return &AtomCallFrame{
 		Caller:  caller,
 		Fn:      fn,
 		Ip:      ip,
-		Env:     NewAtomEnv(fn.Obj.(*AtomCode).Capture),
+		Env:     env,
+		Locals:  make([]*AtomValue, code.Locals),
+		Cells:   nil, // allocated by the first captured local
 		Stack:   NewAtomStack(),
 		Promise: nil,
+		Depth:   depth,
 	}
 }
@@ -1,26 +1,56 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
+	"math/big"
+	"os"
 	"strings"
+
+	"github.com/fatih/color"
 )
 
 const (
+	// TRESHOLD is the default AtomInterpreter.Threshold.
 	TRESHOLD = 1000
+	// DefaultMaxCallDepth bounds the frames of a run, see MaxCallDepth.
+	DefaultMaxCallDepth = 10000
 )
 
 type AtomInterpreter struct {
 	State       *AtomState
 	Scheduler   *AtomScheduler
 	ModuleTable map[string]*AtomValue
+	Modules     map[string]*AtomModule
+	Limits      AtomLimits
+	// MaxCallDepth is the deepest a call may nest before it fails with
+	// "maximum call stack size exceeded", zero disables the check.
+	MaxCallDepth int
+	// Threshold is the number of calls and loop iterations after which
+	// a function runs as threaded code, zero keeps every function in
+	// the bytecode switch.
+	Threshold int
+	budget    *atomBudget
+	runs      int
+	allocated int64
 }
 
 func NewInterpreter(state *AtomState) *AtomInterpreter {
 	interpreter := &AtomInterpreter{
-		State:       state,
-		ModuleTable: map[string]*AtomValue{},
+		State:        state,
+		ModuleTable:  map[string]*AtomValue{},
+		Modules:      map[string]*AtomModule{},
+		MaxCallDepth: DefaultMaxCallDepth,
+		Threshold:    TRESHOLD,
 	}
 	interpreter.Scheduler = NewAtomScheduler(interpreter)
+
+	for _, module := range builtinModules() {
+		interpreter.Modules[module.Name] = module
+		state.SaveNative(module.Name)
+		DefineModule(interpreter, module.Name, module.Values)
+	}
+
 	return interpreter
 }
 
@@ -40,12 +70,45 @@ func StackTrace(frame *AtomCallFrame) string {
 	return builder.String()
 }
 
+// CallTrace lists a frame and its callers, the innermost first and at
+// most limit of them.
+func CallTrace(frame *AtomCallFrame, limit int) string {
+	builder := strings.Builder{}
+	count := 0
+	for current := frame; current != nil; current = current.Caller {
+		if count == limit {
+			builder.WriteString(fmt.Sprintf("\n    ... %d more", current.Depth+1))
+			break
+		}
+		code := current.Fn.Obj.(*AtomCode)
+		builder.WriteString(fmt.Sprintf("\n    at %s (%s:%d)", code.Name, code.File, BinarySearch(code.Line, current.Ip)))
+		count++
+	}
+	return builder.String()
+}
+
+// ExecuteFrame runs a frame until it returns or suspends. Calls between
+// Atom functions are dispatched in the same loop, the callee replaces the
+// current frame and its caller resumes when it returns, so recursion in
+// scripts does not grow the Go stack.
 func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
+	base := frame
+
 	// Frame here is a function
-	var code = frame.Fn.Obj.(*AtomCode)
-	var size = len(code.Code)
-	var strt = frame.Ip
+	var code *AtomCode
+	var size int
+	var strt int
+	var steps []atomStep
+
+	var enter = func(next *AtomCallFrame) {
+		frame = next
+		code = frame.Fn.Obj.(*AtomCode)
+		size = len(code.Code)
+		strt = frame.Ip
+		steps = code.tier.steps
+	}
 
+	enter(frame)
 	i.Scheduler.Running(frame)
 
 	var forwardIp = func(offset int) {
@@ -54,42 +117,64 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 	}
 
 	var jump = func(offset int) {
+		if offset < strt {
+			i.checkpoint()
+			// Loops get hot without calls
+			i.warm(code)
+			steps = code.tier.steps
+		}
 		strt = offset
 		frame.Ip = offset
 	}
 
-	var stringCache = map[int]string{}
-	var intCache = map[int]int{}
-	var numCache = map[int]float64{}
-
-	var writeString = func(offset int) string {
-		save := stringCache[offset]
-		if save == "" {
-			save = ReadStr(code.Code, offset)
-			stringCache[offset] = save
+	// leave resumes the caller after the current frame returned or
+	// suspended, it reports whether the base frame is done instead.
+	var leave = func() bool {
+		if frame == base {
+			return true
+		}
+		callee := frame
+		enter(frame.Caller)
+		if callee.Instance != nil {
+			frame.Stack.Pop()
+			frame.Stack.Push(callee.Instance)
 		}
-		return save
+		// Past the operand of the call
+		forwardIp(4)
+		return false
 	}
 
-	var writeInt = func(offset int) int {
-		save := intCache[offset]
-		if save == 0 {
-			save = ReadInt(code.Code, offset)
-			intCache[offset] = save
-		}
-		return save
+	// constant reads the constant indexed by the operand at offset
+	var constant = func(offset int) *AtomValue {
+		return code.Constants[ReadInt(code.Code, offset)]
 	}
 
-	var writeNum = func(offset int) float64 {
-		save := numCache[offset]
-		if save == 0 {
-			save = ReadNum(code.Code, offset)
-			numCache[offset] = save
+	for {
+		if strt >= size {
+			if leave() {
+				return
+			}
+			continue
+		}
+
+		if i.budget != nil {
+			i.budget.executed++
+		}
+
+		// Threaded code, the frame is shared so both tiers can run it
+		if steps != nil {
+			if step := steps[strt]; step != nil {
+				frame.Ip = strt + 1
+				next := step(i, frame)
+				if next <= strt {
+					i.checkpoint()
+				}
+				strt = next
+				frame.Ip = next
+				continue
+			}
 		}
-		return save
-	}
 
-	for strt < size {
 		opCode := code.Code[strt]
 		forwardIp(1)
 
@@ -100,24 +185,26 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 			forwardIp(4)
 
 		case OpLoadInt:
-			value := writeInt(strt)
+			value := ReadInt(code.Code, strt)
 			frame.Stack.Push(NewAtomValueInt(value))
 			forwardIp(4)
 
 		case OpLoadBigInt:
-			value := writeString(strt)
-			frame.Stack.Push(NewAtomValueBigInt(BigInt(value)))
-			forwardIp(len(value) + 1)
+			value := constant(strt)
+			frame.Stack.Push(NewAtomValueBigInt(new(big.Int).Set(value.Obj.(*big.Int))))
+			i.allocateValue(frame.Stack.Peek())
+			forwardIp(4)
 
 		case OpLoadNum:
-			value := writeNum(strt)
-			frame.Stack.Push(NewAtomValueNum(value))
-			forwardIp(8)
+			// Constants are never modified, push them as they are
+			frame.Stack.Push(constant(strt))
+			forwardIp(4)
 
 		case OpLoadStr:
-			value := writeString(strt)
-			frame.Stack.Push(NewAtomValueStr(value))
-			forwardIp(len(value) + 1)
+			value := constant(strt)
+			frame.Stack.Push(NewAtomValueStr(value.Str))
+			i.allocateValue(frame.Stack.Peek())
+			forwardIp(4)
 
 		case OpLoadBool:
 			if ReadInt(code.Code, strt) != 0 {
@@ -131,27 +218,51 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 			frame.Stack.Push(i.State.NullValue)
 
 		case OpLoadBase:
-			DoLoadBase(i, frame)
+			self := frame.Stack.Pop()
+			DoLoadBase(i, frame, self)
 
 		case OpLoadArray:
 			size := ReadInt(code.Code, strt)
 			DoLoadArray(frame, size)
+			i.allocateValue(frame.Stack.Peek())
 			forwardIp(4)
 
 		case OpLoadObject:
 			size := ReadInt(code.Code, strt)
 			DoLoadObject(frame, size)
+			i.allocateValue(frame.Stack.Peek())
 			forwardIp(4)
 
 		case OpLoadName:
-			index := writeString(strt)
+			index := constant(strt).Str
 			DoLoadName(frame, index)
-			forwardIp(len(index) + 1)
+			forwardIp(4)
+
+		case OpLoadLocal:
+			slot := ReadInt(code.Code, strt)
+			frame.Stack.Push(frame.Locals[slot])
+			forwardIp(4)
+
+		case OpLoadCell:
+			slot := ReadInt(code.Code, strt)
+			frame.Stack.Push(frame.Cells[slot].Value)
+			forwardIp(4)
+
+		case OpLoadCapture:
+			index := ReadInt(code.Code, strt)
+			frame.Stack.Push(code.Cells[index].Value)
+			forwardIp(4)
 
 		case OpLoadModule:
-			name := writeString(strt)
+			name := constant(strt).Str
 			DoLoadModule(i, frame, name)
-			forwardIp(len(name) + 1)
+			forwardIp(4)
+
+		case OpImportModule:
+			file := constant(strt).Str
+			path := frame.Stack.Pop()
+			DoImportModule(i, frame, file, path)
+			forwardIp(4)
 
 		case OpLoadFunction:
 			offset := ReadInt(code.Code, strt)
@@ -160,9 +271,9 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 
 		case OpMakeClass:
 			size := ReadInt(code.Code, strt)
-			name := writeString(strt + 4)
+			name := constant(strt + 4).Str
 			DoMakeClass(i, frame, name, size)
-			forwardIp(4 + len(name) + 1)
+			forwardIp(8)
 
 		case OpExtendClass:
 			ext := frame.Stack.Pop()
@@ -177,20 +288,69 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 		case OpCallConstructor:
 			argc := ReadInt(code.Code, strt)
 			call := frame.Stack.Pop()
-			DoCallConstructor(i, frame, call, argc)
+			i.checkpoint()
+			if callee := EnterConstructor(i, frame, call, argc); callee != nil {
+				enter(callee)
+				i.Scheduler.Running(frame)
+				continue
+			}
+			forwardIp(4)
+
+		case OpCallAttribute:
+			obj := frame.Stack.Pop()
+			cache := &code.Caches[ReadInt(code.Code, strt+4)]
+			call, argc := DoLoadMethod(i, frame, obj, constant(strt), cache, ReadInt(code.Code, strt+8))
+			// Left at the argument count like OpCall
+			forwardIp(8)
+			i.checkpoint()
+			if callee := EnterCall(i, frame, call, argc); callee != nil {
+				enter(callee)
+				i.Scheduler.Running(frame)
+				continue
+			}
 			forwardIp(4)
 
 		case OpCall:
 			argc := ReadInt(code.Code, strt)
 			call := frame.Stack.Pop()
-			DoCall(i, frame, call, argc)
+			i.checkpoint()
+			if callee := EnterCall(i, frame, call, argc); callee != nil {
+				enter(callee)
+				i.Scheduler.Running(frame)
+				continue
+			}
 			forwardIp(4)
 
+		case OpTailCall:
+			argc := ReadInt(code.Code, strt)
+			call := frame.Stack.Pop()
+			i.checkpoint()
+			callee := EnterCall(i, frame, call, argc)
+			if callee == nil {
+				// Native, the result is returned by the next OpReturn
+				forwardIp(4)
+				continue
+			}
+			if code.Async || callee.Fn.Obj.(*AtomCode).Async {
+				// Promises are resolved by the caller, call normally
+				enter(callee)
+				i.Scheduler.Running(frame)
+				continue
+			}
+			// Reuse the frame, the caller receives the callee's result
+			frame.Fn = callee.Fn
+			frame.Env = callee.Env
+			frame.Locals = callee.Locals
+			frame.Cells = callee.Cells
+			frame.Stack = callee.Stack
+			frame.Ip = 0
+			enter(frame)
+
 		case OpAwait:
 			if !CheckType(frame.Stack.Peek(), AtomTypePromise) {
 				continue
 			}
-			if i.Scheduler.Await(frame) {
+			if i.Scheduler.Await(frame) && leave() {
 				return
 			}
 
@@ -228,15 +388,21 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 			DoIndex(i, frame, obj, idx)
 
 		case OpPluckAttribute:
-			att := writeString(strt)
+			att := constant(strt).Str
 			obj := frame.Stack.Peek()
 			DoPluckAttribute(i, frame, obj, att)
-			forwardIp(len(att) + 1)
+			forwardIp(4)
+
+		case OpLoadAttribute:
+			obj := frame.Stack.Pop()
+			DoLoadAttribute(i, frame, obj, constant(strt), &code.Caches[ReadInt(code.Code, strt+4)])
+			forwardIp(8)
 
 		case OpMul:
 			rhs := frame.Stack.Pop()
 			lhs := frame.Stack.Pop()
 			DoMultiplication(frame, lhs, rhs)
+			i.allocateValue(frame.Stack.Peek())
 
 		case OpDiv:
 			rhs := frame.Stack.Pop()
@@ -252,16 +418,19 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 			rhs := frame.Stack.Pop()
 			lhs := frame.Stack.Pop()
 			DoAddition(frame, lhs, rhs)
+			i.allocateValue(frame.Stack.Peek())
 
 		case OpSub:
 			rhs := frame.Stack.Pop()
 			lhs := frame.Stack.Pop()
 			DoSubtraction(frame, lhs, rhs)
+			i.allocateValue(frame.Stack.Peek())
 
 		case OpShl:
 			rhs := frame.Stack.Pop()
 			lhs := frame.Stack.Pop()
 			DoShiftLeft(frame, lhs, rhs)
+			i.allocateValue(frame.Stack.Peek())
 
 		case OpShr:
 			rhs := frame.Stack.Pop()
@@ -314,26 +483,69 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 			DoXor(frame, lhs, rhs)
 
 		case OpStoreModule:
-			name := writeString(strt)
+			name := constant(strt).Str
 			DoStoreModule(i, frame, name)
-			forwardIp(len(name) + 1)
+			forwardIp(4)
 
-		case OpInitLocal:
-			index := writeString(strt)
+		case OpInitName:
+			index := constant(strt).Str
 			value := frame.Stack.Pop()
-			DoInitLocal(i, frame, index, value)
-			forwardIp(len(index) + 1)
+			DoInitName(i, frame, index, value)
+			forwardIp(4)
 
-		case OpStoreLocal:
-			index := writeString(strt)
+		case OpStoreName:
+			index := constant(strt).Str
 			value := frame.Stack.Pop()
-			DoStoreLocal(i, frame, index, value)
-			forwardIp(len(index) + 1)
+			DoStoreName(i, frame, index, value)
+			forwardIp(4)
+
+		case OpInitLocal, OpStoreLocal:
+			slot := ReadInt(code.Code, strt)
+			frame.Locals[slot] = frame.Stack.Pop()
+			forwardIp(4)
+
+		case OpInitCell:
+			slot := ReadInt(code.Code, strt)
+			DoInitCell(frame, slot, frame.Stack.Pop())
+			forwardIp(4)
+
+		case OpStoreCell:
+			slot := ReadInt(code.Code, strt)
+			frame.Cells[slot].Value = frame.Stack.Pop()
+			forwardIp(4)
+
+		case OpStoreCapture:
+			index := ReadInt(code.Code, strt)
+			code.Cells[index].Value = frame.Stack.Pop()
+			forwardIp(4)
+
+		case OpIncLocal:
+			slot := ReadInt(code.Code, strt)
+			DoAddition(frame, frame.Locals[slot], NewAtomValueInt(1))
+			i.allocateValue(frame.Stack.Peek())
+			frame.Locals[slot] = frame.Stack.Pop()
+			forwardIp(4)
+
+		case OpDecLocal:
+			slot := ReadInt(code.Code, strt)
+			DoSubtraction(frame, frame.Locals[slot], NewAtomValueInt(1))
+			i.allocateValue(frame.Stack.Peek())
+			frame.Locals[slot] = frame.Stack.Pop()
+			forwardIp(4)
 
 		case OpSetIndex:
 			idx := frame.Stack.Pop()
 			obj := frame.Stack.Pop()
+			before := SizeOf(obj)
 			DoSetIndex(i, frame, obj, idx)
+			i.allocateGrowth(obj, before)
+
+		case OpStoreAttribute:
+			obj := frame.Stack.Pop()
+			before := SizeOf(obj)
+			DoStoreAttribute(i, frame, obj, constant(strt), &code.Caches[ReadInt(code.Code, strt+4)])
+			i.allocateGrowth(obj, before)
+			forwardIp(8)
 
 		case OpJumpIfFalseOrPop:
 			offset := ReadInt(code.Code, strt)
@@ -399,6 +611,20 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 			forwardIp(4)
 			jump(offset)
 
+		case OpCmpLtJumpIfFalse,
+			OpCmpLteJumpIfFalse,
+			OpCmpGtJumpIfFalse,
+			OpCmpGteJumpIfFalse,
+			OpCmpEqJumpIfFalse,
+			OpCmpNeJumpIfFalse:
+			offset := ReadInt(code.Code, strt)
+			forwardIp(4)
+			rhs := frame.Stack.Pop()
+			lhs := frame.Stack.Pop()
+			if !DoCompare(i, frame, opCode, lhs, rhs) {
+				jump(offset)
+			}
+
 		case OpDupTop:
 			frame.Stack.Push(frame.Stack.Peek())
 
@@ -416,18 +642,6 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 		case OpPopTop:
 			frame.Stack.Pop()
 
-		case OpEnterBlock:
-			_ = ReadInt(code.Code, strt)
-			forwardIp(4)
-			frame.Env = NewAtomEnv(frame.Env)
-
-		case OpExitBlock:
-			depth := ReadInt(code.Code, strt)
-			forwardIp(4)
-			for range depth {
-				frame.Env = frame.Env.Parent
-			}
-
 		case OpRot2:
 			DoRot2(frame)
 
@@ -443,7 +657,9 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 				panic(fmt.Sprintf("%s: Return with more than 1 value on the stack %d", frame.Fn.Obj.(*AtomCode).Name, frame.Stack.Len()))
 			}
 			i.Scheduler.Resolve(frame)
-			return
+			if leave() {
+				return
+			}
 
 		default:
 			// fmt.Println(Decompile(code))
@@ -453,18 +669,83 @@ func (i *AtomInterpreter) ExecuteFrame(frame *AtomCallFrame) {
 }
 
 func (i *AtomInterpreter) Interpret(atomFunc *AtomValue) {
-	DefineModule(i, "std", EXPORT_STD)
-	DefineModule(i, "object", EXPORT_OBJECT)
-	DefineModule(i, "math", EXPORT_MATH)
-	DefineModule(i, "path", EXPORT_PATH)
-	DefineModule(i, "os", EXPORT_OS)
-	DefineModule(i, "file", EXPORT_FILE)
-	DefineModule(i, "string", EXPORT_STRING)
-	DefineModule(i, "number", EXPORT_NUMBER)
-	DefineModule(i, "GinBinding", EXPORT_GIN)
+	if err := i.Run(atomFunc, nil); err != nil {
+		fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+		os.Exit(1)
+	}
+}
+
+// Run executes a compiled program and drains the scheduler. When env is
+// not nil the program declares its globals there, so they outlive the run.
+// Uncaught errors are returned instead of terminating the process.
+func (i *AtomInterpreter) Run(atomFunc *AtomValue, env *AtomEnv) error {
+	return i.RunContext(context.Background(), atomFunc, env)
+}
+
+// RunContext is Run aborted when ctx is done or the limits
+// of the interpreter are exceeded, see AtomAbortError.
+func (i *AtomInterpreter) RunContext(ctx context.Context, atomFunc *AtomValue, env *AtomEnv) (err error) {
+	defer i.recoverError(&err)
+	defer i.begin(ctx)()
+
+	frame := NewAtomCallFrame(nil, atomFunc, 0)
+	if env != nil {
+		frame.Env = env
+	}
 
 	// Run while the frame is not empty
-	i.ExecuteFrame(NewAtomCallFrame(nil, atomFunc, 0))
+	i.ExecuteFrame(frame)
+
+	i.Scheduler.Run()
+	return nil
+}
+
+// Call invokes a callable value on behalf of the host and returns
+// its result, awaiting it when the callee is an async function.
+func (i *AtomInterpreter) Call(fn *AtomValue, args ...*AtomValue) (*AtomValue, error) {
+	return i.CallContext(context.Background(), fn, args...)
+}
 
+// CallContext is Call with the budget of RunContext.
+func (i *AtomInterpreter) CallContext(ctx context.Context, fn *AtomValue, args ...*AtomValue) (result *AtomValue, err error) {
+	defer i.recoverError(&err)
+	defer i.begin(ctx)()
+
+	host := NewAtomCallFrame(nil, NewAtomGenericValue(
+		AtomTypeFunc,
+		NewAtomCode("<host>", "host", false, 0),
+	), 0)
+
+	for _, arg := range args {
+		host.Stack.Push(arg)
+	}
+
+	DoCall(i, host, fn, len(args))
 	i.Scheduler.Run()
+
+	result = host.Stack.Pop()
+	if CheckType(result, AtomTypePromise) && result.Obj.(*AtomPromise).IsFulfilled() {
+		result = result.Obj.(*AtomPromise).Value
+	}
+	if CheckType(result, AtomTypeErr) {
+		return nil, NewAtomRuntimeError(result.String())
+	}
+	return result, nil
+}
+
+func (i *AtomInterpreter) recoverError(err *error) {
+	if r := recover(); r != nil {
+		// Pending tasks belong to the failed run
+		i.Scheduler.MicroTask = []*AtomCallFrame{}
+
+		if runtimeError, ok := r.(*AtomRuntimeError); ok {
+			*err = runtimeError
+			return
+		}
+		if abortError, ok := r.(*AtomAbortError); ok {
+			*err = abortError
+			return
+		}
+		*err = NewAtomRuntimeError(fmt.Sprint(r))
+	}
 }
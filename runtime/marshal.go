@@ -0,0 +1,630 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	bigFloatType = reflect.TypeOf((*big.Float)(nil))
+)
+
+/*
+ * Conversion between Go values and Atom values.
+ *
+ *	Go                              Atom
+ *	bool                            bool
+ *	int*, uint*                     int, big number when it overflows int32
+ *	float*, *big.Float              number
+ *	*big.Int                        big number
+ *	string, []byte                  string
+ *	time.Time                       string (RFC 3339)
+ *	slice, array                    array
+ *	map, struct                     object
+ *	pointer, interface              the value it holds, null when nil
+ *
+ * Struct fields are named after their `atom:"name"` tag, or the field
+ * name. `atom:"-"` skips a field and `atom:"name,omitempty"` skips its
+ * zero value. Map keys may be strings, numbers or bools.
+ */
+
+// Marshal converts a Go value to an Atom value.
+func Marshal(value any) (*AtomValue, error) {
+	result, _, err := marshalSized(value)
+	return result, err
+}
+
+// marshalSized converts value like Marshal and also returns the bytes
+// of the values it created inside the result, see SizeOf. Values the
+// result already held as *AtomValue are not counted.
+func marshalSized(value any) (*AtomValue, int64, error) {
+	marshaler := &atomMarshaler{visiting: map[marshalVisit]bool{}}
+	result, err := marshaler.marshal(reflect.ValueOf(value), "")
+	return result, marshaler.nested, err
+}
+
+// Unmarshal stores an Atom value in the Go value target points to. An
+// `any` target receives bool, int, float64, *big.Int, string, []any,
+// map[string]any or nil.
+func Unmarshal(value *AtomValue, target any) error {
+	pointer := reflect.ValueOf(target)
+	if pointer.Kind() != reflect.Pointer || pointer.IsNil() {
+		return NewAtomMarshalError("", fmt.Sprintf("unmarshal target must be a non-nil pointer, got %T", target))
+	}
+	unmarshaler := &atomUnmarshaler{visiting: map[*AtomValue]bool{}}
+	return unmarshaler.unmarshal(value, pointer.Elem(), "")
+}
+
+// marshalVisit is a pointer, map or slice being converted. Slices
+// are told apart by length too, s and s[:1] share their data pointer.
+type marshalVisit struct {
+	pointer uintptr
+	length  int
+}
+
+type atomMarshaler struct {
+	visiting map[marshalVisit]bool
+	depth    int   // Containers the value being converted is inside of
+	nested   int64 // Bytes of the created values below the root
+}
+
+// created counts a value the marshaler made, the root is left to the
+// caller which counts it like any other result.
+func (m *atomMarshaler) created(value *AtomValue) *AtomValue {
+	if m.depth > 0 && !isShared(value) {
+		m.nested += SizeOf(value)
+	}
+	return value
+}
+
+// element converts a value held by a container.
+func (m *atomMarshaler) element(value reflect.Value, path string) (*AtomValue, error) {
+	m.depth++
+	defer func() { m.depth-- }()
+	return m.marshal(value, path)
+}
+
+// enter fails when value is already being converted, a value that
+// contains itself would recurse forever.
+func (m *atomMarshaler) enter(value reflect.Value, path string) (marshalVisit, error) {
+	visit := marshalVisit{pointer: value.Pointer()}
+	if value.Kind() == reflect.Slice {
+		visit.length = value.Len()
+	}
+	if m.visiting[visit] {
+		return visit, NewAtomMarshalError(path, "cyclic value")
+	}
+	m.visiting[visit] = true
+	return visit, nil
+}
+
+func (m *atomMarshaler) marshal(value reflect.Value, path string) (*AtomValue, error) {
+	if !value.IsValid() {
+		return NewAtomValueNull(), nil
+	}
+
+	switch value.Type() {
+	case valueType:
+		if value.IsNil() {
+			return NewAtomValueNull(), nil
+		}
+		return value.Interface().(*AtomValue), nil
+	case bigIntType:
+		if value.IsNil() {
+			return NewAtomValueNull(), nil
+		}
+		return m.created(NewAtomValueBigInt(new(big.Int).Set(value.Interface().(*big.Int)))), nil
+	case bigFloatType:
+		if value.IsNil() {
+			return NewAtomValueNull(), nil
+		}
+		number, _ := value.Interface().(*big.Float).Float64()
+		return m.created(NewAtomValueNum(number)), nil
+	case timeType:
+		return m.created(NewAtomValueStr(value.Interface().(time.Time).Format(time.RFC3339Nano))), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			return NewAtomValueTrue(), nil
+		}
+		return NewAtomValueFalse(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Int() < math.MinInt32 || value.Int() > math.MaxInt32 {
+			return m.created(NewAtomValueBigInt(big.NewInt(value.Int()))), nil
+		}
+		return m.created(NewAtomValueInt(int(value.Int()))), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value.Uint() > math.MaxInt32 {
+			return m.created(NewAtomValueBigInt(new(big.Int).SetUint64(value.Uint()))), nil
+		}
+		return m.created(NewAtomValueInt(int(value.Uint()))), nil
+
+	case reflect.Float32, reflect.Float64:
+		return m.created(NewAtomValueNum(value.Float())), nil
+
+	case reflect.String:
+		return m.created(NewAtomValueStr(value.String())), nil
+
+	case reflect.Pointer:
+		if value.IsNil() {
+			return NewAtomValueNull(), nil
+		}
+		visit, err := m.enter(value, path)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(m.visiting, visit)
+		return m.marshal(value.Elem(), path)
+
+	case reflect.Interface:
+		if value.IsNil() {
+			return NewAtomValueNull(), nil
+		}
+		return m.marshal(value.Elem(), path)
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return NewAtomValueNull(), nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			bytes := make([]byte, value.Len())
+			reflect.Copy(reflect.ValueOf(bytes), value)
+			return m.created(NewAtomValueStr(string(bytes))), nil
+		}
+		if value.Kind() == reflect.Slice && value.Len() > 0 {
+			visit, err := m.enter(value, path)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(m.visiting, visit)
+		}
+		elements := make([]*AtomValue, value.Len())
+		for i := range elements {
+			element, err := m.element(value.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return m.created(NewAtomGenericValue(AtomTypeArray, NewAtomArray(elements))), nil
+
+	case reflect.Map:
+		if value.IsNil() {
+			return NewAtomValueNull(), nil
+		}
+		visit, err := m.enter(value, path)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(m.visiting, visit)
+		elements := map[string]*AtomValue{}
+		iterator := value.MapRange()
+		for iterator.Next() {
+			key, err := formatMapKey(iterator.Key())
+			if err != nil {
+				return nil, NewAtomMarshalError(path, err.Error())
+			}
+			element, err := m.element(iterator.Value(), joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			elements[key] = element
+		}
+		return m.created(NewAtomGenericValue(AtomTypeObj, NewAtomObject(elements))), nil
+
+	case reflect.Struct:
+		elements := map[string]*AtomValue{}
+		for _, field := range structFields(value.Type()) {
+			fieldValue := value.FieldByIndex(field.index)
+			if field.omitEmpty && fieldValue.IsZero() {
+				continue
+			}
+			element, err := m.element(fieldValue, joinPath(path, field.name))
+			if err != nil {
+				return nil, err
+			}
+			elements[field.name] = element
+		}
+		return m.created(NewAtomGenericValue(AtomTypeObj, NewAtomObject(elements))), nil
+	}
+
+	return nil, NewAtomMarshalError(path, fmt.Sprintf("unsupported type %s", value.Type()))
+}
+
+type atomUnmarshaler struct {
+	visiting map[*AtomValue]bool
+}
+
+func (u *atomUnmarshaler) unmarshal(value *AtomValue, target reflect.Value, path string) error {
+	targetType := target.Type()
+
+	switch targetType {
+	case valueType:
+		target.Set(reflect.ValueOf(value))
+		return nil
+	case bigIntType:
+		if CheckType(value, AtomTypeNull) {
+			target.SetZero()
+			return nil
+		}
+		if !CheckType(value, AtomTypeInt) && !CheckType(value, AtomTypeBigInt) {
+			return mismatchError(path, "big number", value)
+		}
+		target.Set(reflect.ValueOf(new(big.Int).Set(CoerceToBigInt(value))))
+		return nil
+	case bigFloatType:
+		if CheckType(value, AtomTypeNull) {
+			target.SetZero()
+			return nil
+		}
+		if !IsNumberType(value) {
+			return mismatchError(path, "number", value)
+		}
+		if CheckType(value, AtomTypeBigInt) {
+			target.Set(reflect.ValueOf(new(big.Float).SetInt(CoerceToBigInt(value))))
+		} else {
+			target.Set(reflect.ValueOf(big.NewFloat(CoerceToNum(value))))
+		}
+		return nil
+	case timeType:
+		if !CheckType(value, AtomTypeStr) {
+			return mismatchError(path, "string", value)
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, value.Str)
+		if err != nil {
+			return NewAtomMarshalError(path, fmt.Sprintf("invalid time %q", value.Str))
+		}
+		target.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	switch targetType.Kind() {
+	case reflect.Bool:
+		if !CheckType(value, AtomTypeBool) {
+			return mismatchError(path, "bool", value)
+		}
+		target.SetBool(value.I32 == 1)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer, ok := toBigInteger(value)
+		if !ok {
+			return mismatchError(path, "int", value)
+		}
+		if !integer.IsInt64() || target.OverflowInt(integer.Int64()) {
+			return NewAtomMarshalError(path, fmt.Sprintf("%s overflows %s", integer, targetType))
+		}
+		target.SetInt(integer.Int64())
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		integer, ok := toBigInteger(value)
+		if !ok {
+			return mismatchError(path, "int", value)
+		}
+		if !integer.IsUint64() || target.OverflowUint(integer.Uint64()) {
+			return NewAtomMarshalError(path, fmt.Sprintf("%s overflows %s", integer, targetType))
+		}
+		target.SetUint(integer.Uint64())
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		if !IsNumberType(value) {
+			return mismatchError(path, "number", value)
+		}
+		target.SetFloat(CoerceToNum(value))
+		return nil
+
+	case reflect.String:
+		if !CheckType(value, AtomTypeStr) {
+			return mismatchError(path, "string", value)
+		}
+		target.SetString(value.Str)
+		return nil
+
+	case reflect.Pointer:
+		if CheckType(value, AtomTypeNull) {
+			target.SetZero()
+			return nil
+		}
+		element := reflect.New(targetType.Elem())
+		if err := u.unmarshal(value, element.Elem(), path); err != nil {
+			return err
+		}
+		target.Set(element)
+		return nil
+
+	case reflect.Interface:
+		if targetType.NumMethod() != 0 {
+			return NewAtomMarshalError(path, fmt.Sprintf("unsupported type %s", targetType))
+		}
+		generic, err := u.generic(value, path)
+		if err != nil {
+			return err
+		}
+		if generic == nil {
+			target.SetZero()
+		} else {
+			target.Set(reflect.ValueOf(generic))
+		}
+		return nil
+
+	case reflect.Slice:
+		if CheckType(value, AtomTypeNull) {
+			target.SetZero()
+			return nil
+		}
+		if targetType.Elem().Kind() == reflect.Uint8 && CheckType(value, AtomTypeStr) {
+			target.SetBytes([]byte(value.Str))
+			return nil
+		}
+		if !CheckType(value, AtomTypeArray) {
+			return mismatchError(path, "array", value)
+		}
+		elements := value.Obj.(*AtomArray).Elements
+		slice := reflect.MakeSlice(targetType, len(elements), len(elements))
+		if err := u.enter(value, path); err != nil {
+			return err
+		}
+		defer u.leave(value)
+		for i, element := range elements {
+			if err := u.unmarshal(element, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+
+	case reflect.Array:
+		if !CheckType(value, AtomTypeArray) {
+			return mismatchError(path, "array", value)
+		}
+		elements := value.Obj.(*AtomArray).Elements
+		if len(elements) != targetType.Len() {
+			return NewAtomMarshalError(path, fmt.Sprintf("expected %d elements, got %d", targetType.Len(), len(elements)))
+		}
+		if err := u.enter(value, path); err != nil {
+			return err
+		}
+		defer u.leave(value)
+		for i, element := range elements {
+			if err := u.unmarshal(element, target.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if CheckType(value, AtomTypeNull) {
+			target.SetZero()
+			return nil
+		}
+		properties, ok := objectProperties(value)
+		if !ok {
+			return mismatchError(path, "object", value)
+		}
+		if err := u.enter(value, path); err != nil {
+			return err
+		}
+		defer u.leave(value)
+		result := reflect.MakeMapWithSize(targetType, len(properties))
+		for key, property := range properties {
+			mapKey, err := parseMapKey(key, targetType.Key())
+			if err != nil {
+				return NewAtomMarshalError(joinPath(path, key), err.Error())
+			}
+			element := reflect.New(targetType.Elem()).Elem()
+			if err := u.unmarshal(property, element, joinPath(path, key)); err != nil {
+				return err
+			}
+			result.SetMapIndex(mapKey, element)
+		}
+		target.Set(result)
+		return nil
+
+	case reflect.Struct:
+		properties, ok := objectProperties(value)
+		if !ok {
+			return mismatchError(path, "object", value)
+		}
+		if err := u.enter(value, path); err != nil {
+			return err
+		}
+		defer u.leave(value)
+		for _, field := range structFields(targetType) {
+			property, exists := properties[field.name]
+			if !exists {
+				continue
+			}
+			if err := u.unmarshal(property, target.FieldByIndex(field.index), joinPath(path, field.name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return NewAtomMarshalError(path, fmt.Sprintf("unsupported type %s", targetType))
+}
+
+// generic converts a value for an `any` target.
+func (u *atomUnmarshaler) generic(value *AtomValue, path string) (any, error) {
+	switch value.Type {
+	case AtomTypeNull:
+		return nil, nil
+	case AtomTypeInt:
+		return int(value.I32), nil
+	case AtomTypeNum:
+		return value.F64, nil
+	case AtomTypeBigInt:
+		return new(big.Int).Set(value.Obj.(*big.Int)), nil
+	case AtomTypeBool:
+		return value.I32 == 1, nil
+	case AtomTypeStr:
+		return value.Str, nil
+	case AtomTypeArray:
+		result := []any{}
+		err := u.unmarshal(value, reflect.ValueOf(&result).Elem(), path)
+		return result, err
+	case AtomTypeObj, AtomTypeEnum, AtomTypeClassInstance:
+		// Instances become the map of their fields only
+		result := map[string]any{}
+		err := u.unmarshal(value, reflect.ValueOf(&result).Elem(), path)
+		return result, err
+	}
+	return nil, NewAtomMarshalError(path, fmt.Sprintf("cannot convert %s to a Go value", GetTypeString(value)))
+}
+
+func (u *atomUnmarshaler) enter(value *AtomValue, path string) error {
+	if u.visiting[value] {
+		return NewAtomMarshalError(path, "cyclic value")
+	}
+	u.visiting[value] = true
+	return nil
+}
+
+func (u *atomUnmarshaler) leave(value *AtomValue) {
+	delete(u.visiting, value)
+}
+
+// objectProperties returns the properties of an object, enum or
+// instance of a user defined class.
+func objectProperties(value *AtomValue) (map[string]*AtomValue, bool) {
+	switch value.Type {
+	case AtomTypeObj, AtomTypeEnum:
+		return value.Obj.(*AtomObject).Elements, true
+	case AtomTypeClassInstance:
+		instance := value.Obj.(*AtomClassInstance)
+		if instance.Native != nil {
+			return nil, false
+		}
+		return instance.Properties(), true
+	}
+	return nil, false
+}
+
+func toBigInteger(value *AtomValue) (*big.Int, bool) {
+	switch value.Type {
+	case AtomTypeInt:
+		return big.NewInt(int64(value.I32)), true
+	case AtomTypeBigInt:
+		return value.Obj.(*big.Int), true
+	case AtomTypeNum:
+		if !IsInteger(value.F64) || math.IsInf(value.F64, 0) {
+			return nil, false
+		}
+		integer, _ := big.NewFloat(value.F64).Int(nil)
+		return integer, true
+	}
+	return nil, false
+}
+
+type atomField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields lists the exported fields of a struct, the fields of
+// untagged embedded structs are promoted.
+func structFields(structType reflect.Type) []atomField {
+	fields := []atomField{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("atom")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for _, promoted := range structFields(field.Type) {
+				promoted.index = append([]int{i}, promoted.index...)
+				fields = append(fields, promoted)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, atomField{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: options == "omitempty",
+		})
+	}
+	return fields
+}
+
+func formatMapKey(key reflect.Value) (string, error) {
+	switch key.Kind() {
+	case reflect.String:
+		return key.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(key.Float(), 'g', -1, key.Type().Bits()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(key.Bool()), nil
+	}
+	return "", fmt.Errorf("unsupported map key type %s", key.Type())
+}
+
+func parseMapKey(key string, keyType reflect.Type) (reflect.Value, error) {
+	result := reflect.New(keyType).Elem()
+	var err error
+	switch keyType.Kind() {
+	case reflect.String:
+		result.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var parsed int64
+		if parsed, err = strconv.ParseInt(key, 10, keyType.Bits()); err == nil {
+			result.SetInt(parsed)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var parsed uint64
+		if parsed, err = strconv.ParseUint(key, 10, keyType.Bits()); err == nil {
+			result.SetUint(parsed)
+		}
+	case reflect.Float32, reflect.Float64:
+		var parsed float64
+		if parsed, err = strconv.ParseFloat(key, keyType.Bits()); err == nil {
+			result.SetFloat(parsed)
+		}
+	case reflect.Bool:
+		var parsed bool
+		if parsed, err = strconv.ParseBool(key); err == nil {
+			result.SetBool(parsed)
+		}
+	default:
+		return result, fmt.Errorf("unsupported map key type %s", keyType)
+	}
+	if err != nil {
+		return result, fmt.Errorf("invalid %s key %q", keyType, key)
+	}
+	return result, nil
+}
+
+func mismatchError(path string, expected string, value *AtomValue) error {
+	return NewAtomMarshalError(path, fmt.Sprintf("expected %s, got %s", expected, GetTypeString(value)))
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
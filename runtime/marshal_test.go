@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type marshalAddress struct {
+	City string `atom:"city"`
+}
+
+type marshalUser struct {
+	Name      string            `atom:"name"`
+	Age       int               `atom:"age"`
+	Score     float64           `atom:"score"`
+	Admin     bool              `atom:"admin,omitempty"`
+	Password  string            `atom:"-"`
+	Tags      []string          `atom:"tags"`
+	Address   *marshalAddress   `atom:"address"`
+	Limits    map[string]int    `atom:"limits"`
+	Balance   *big.Int          `atom:"balance"`
+	CreatedAt time.Time         `atom:"createdAt"`
+	Avatar    []byte            `atom:"avatar"`
+	Extra     map[string]string `atom:"extra"`
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	balance, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	user := marshalUser{
+		Name:      "Ada",
+		Age:       36,
+		Score:     9.5,
+		Password:  "secret",
+		Tags:      []string{"math", "engines"},
+		Address:   &marshalAddress{City: "London"},
+		Limits:    map[string]int{"requests": 100},
+		Balance:   balance,
+		CreatedAt: time.Date(1843, 7, 10, 12, 0, 0, 0, time.UTC),
+		Avatar:    []byte("png"),
+	}
+
+	value, err := Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties := value.Obj.(*AtomObject).Elements
+	if _, exists := properties["password"]; exists {
+		t.Error("fields tagged - should be skipped")
+	}
+	if _, exists := properties["admin"]; exists {
+		t.Error("zero omitempty fields should be skipped")
+	}
+	if !CheckType(properties["balance"], AtomTypeBigInt) || !CheckType(properties["extra"], AtomTypeNull) {
+		t.Errorf("balance %s, extra %s", GetTypeString(properties["balance"]), GetTypeString(properties["extra"]))
+	}
+
+	var decoded marshalUser
+	if err := Unmarshal(value, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	user.Password = ""
+	if !reflect.DeepEqual(decoded, user) {
+		t.Fatalf("decoded %+v, want %+v", decoded, user)
+	}
+}
+
+func TestUnmarshalAny(t *testing.T) {
+	class := NewAtomGenericValue(AtomTypeClass, NewAtomClass("Point", nil, NewAtomGenericValue(
+		AtomTypeObj,
+		NewAtomObject(map[string]*AtomValue{}),
+	)))
+	point := NewAtomGenericValue(AtomTypeClassInstance, NewAtomClassInstance(class))
+	point.Obj.(*AtomClassInstance).Set("x", NewAtomValueInt(1))
+
+	value := NewAtomGenericValue(AtomTypeArray, NewAtomArray([]*AtomValue{
+		NewAtomValueInt(1),
+		NewAtomValueNum(2.5),
+		NewAtomValueStr("three"),
+		NewAtomValueTrue(),
+		NewAtomValueNull(),
+		point,
+	}))
+
+	var decoded any
+	if err := Unmarshal(value, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	want := []any{1, 2.5, "three", true, nil, map[string]any{"x": 1}}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Fatalf("decoded %#v, want %#v", decoded, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	items := NewAtomGenericValue(AtomTypeArray, NewAtomArray([]*AtomValue{
+		NewAtomValueInt(1),
+		NewAtomValueStr("two"),
+	}))
+	var numbers []int
+	expectMarshalError(t, Unmarshal(items, &numbers), "[1]", "expected int, got string")
+
+	var small int8
+	expectMarshalError(t, Unmarshal(NewAtomValueInt(300), &small), "", "300 overflows int8")
+
+	var target int
+	expectMarshalError(t, Unmarshal(NewAtomValueInt(1), target), "", "unmarshal target must be a non-nil pointer, got int")
+
+	cyclic := NewAtomGenericValue(AtomTypeArray, NewAtomArray([]*AtomValue{}))
+	cyclic.Obj.(*AtomArray).Elements = append(cyclic.Obj.(*AtomArray).Elements, cyclic)
+	var decoded any
+	expectMarshalError(t, Unmarshal(cyclic, &decoded), "[0]", "cyclic value")
+}
+
+func TestMarshalCycles(t *testing.T) {
+	type node struct {
+		Next *node `atom:"next"`
+	}
+	loop := &node{}
+	loop.Next = loop
+	_, err := Marshal(loop)
+	expectMarshalError(t, err, "next", "cyclic value")
+
+	self := map[string]any{}
+	self["self"] = self
+	_, err = Marshal(self)
+	expectMarshalError(t, err, "self", "cyclic value")
+
+	slice := []any{nil}
+	slice[0] = slice
+	_, err = Marshal(slice)
+	expectMarshalError(t, err, "[0]", "cyclic value")
+
+	// Shared values that do not contain themselves are fine
+	shared := []int{1, 2}
+	if _, err := Marshal(map[string]any{"a": shared, "b": shared, "c": shared[:1]}); err != nil {
+		t.Fatalf("shared slice: %v", err)
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	_, err := Marshal(map[string]any{"callback": func() {}})
+	expectMarshalError(t, err, "callback", "unsupported type func()")
+}
+
+func expectMarshalError(t *testing.T, err error, path string, message string) {
+	t.Helper()
+	var marshalError *AtomMarshalError
+	if !errors.As(err, &marshalError) {
+		t.Fatalf("error %v, want an AtomMarshalError", err)
+	}
+	if marshalError.Path != path || marshalError.Message != message {
+		t.Fatalf("error %q at %q, want %q at %q", marshalError.Message, marshalError.Path, message, path)
+	}
+}
+
+func TestMarshalCountsNestedValues(t *testing.T) {
+	shared := NewAtomValueStr("shared")
+	value, nested, err := marshalSized(map[string]any{
+		"words":  []string{"ab", "cd"},
+		"flag":   true,
+		"shared": shared,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := value.Obj.(*AtomObject).Elements["words"]
+	// The array and its strings, not the root, the bool or the value passed through
+	want := SizeOf(words) + 2*SizeOf(NewAtomValueStr("ab"))
+	if nested != want {
+		t.Fatalf("nested = %d, want %d", nested, want)
+	}
+}
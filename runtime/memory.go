@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrMemoryLimit is the cause of runs stopped by AtomLimits.Memory.
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// Approximate sizes in bytes, the memory accounting only has to grow
+// with what scripts allocate, not to match the Go heap.
+const (
+	valueSize   = 48
+	elementSize = 8
+	entrySize   = 48
+)
+
+// SizeOf estimates the bytes a value holds on its own, the values it
+// refers to are counted when they are created.
+func SizeOf(value *AtomValue) int64 {
+	switch value.Type {
+	case AtomTypeStr:
+		if meta, ok := value.Obj.(*atomStr); ok {
+			return valueSize + int64(len(value.Str)-meta.shared)
+		}
+		return valueSize + int64(len(value.Str))
+	case AtomTypeBigInt:
+		return valueSize + int64(len(value.Obj.(*big.Int).Bits()))*elementSize
+	case AtomTypeArray:
+		return valueSize + int64(cap(value.Obj.(*AtomArray).Elements))*elementSize
+	case AtomTypeObj, AtomTypeEnum:
+		return valueSize + int64(len(value.Obj.(*AtomObject).Elements))*entrySize
+	case AtomTypeClassInstance:
+		return valueSize + int64(cap(value.Obj.(*AtomClassInstance).Fields))*elementSize
+	}
+	return valueSize
+}
+
+// MemoryUsage is the approximate number of bytes allocated by the
+// current run, or by the last one when the interpreter is idle.
+func (i *AtomInterpreter) MemoryUsage() int64 {
+	return i.allocated
+}
+
+// allocate records an allocation, it aborts the run when it
+// exceeds AtomLimits.Memory.
+func (i *AtomInterpreter) allocate(size int64) {
+	i.allocated += size
+	if i.Limits.Memory > 0 && i.allocated > i.Limits.Memory && i.runs > 0 {
+		panic(NewAtomAbortError(ErrMemoryLimit))
+	}
+}
+
+// allocateValue records the allocation of a new value,
+// preallocated values cost nothing.
+func (i *AtomInterpreter) allocateValue(value *AtomValue) {
+	if isShared(value) {
+		return
+	}
+	i.allocate(SizeOf(value))
+}
+
+// allocateGrowth records what a container gained since
+// its size was before.
+func (i *AtomInterpreter) allocateGrowth(value *AtomValue, before int64) {
+	if grown := SizeOf(value) - before; grown > 0 {
+		i.allocate(grown)
+	}
+}
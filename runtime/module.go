@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var moduleNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// AtomModule is a native module that scripts load with
+// `import "atom:<name>"`, it is registered per interpreter.
+type AtomModule struct {
+	Name   string
+	Values map[string]*AtomValue
+}
+
+func NewAtomModule(name string) *AtomModule {
+	return &AtomModule{
+		Name:   name,
+		Values: map[string]*AtomValue{},
+	}
+}
+
+// Define adds a value (constant, class, object...) to the module.
+func (m *AtomModule) Define(name string, value *AtomValue) *AtomModule {
+	m.Values[name] = value
+	return m
+}
+
+// DefineFunc adds a native function to the module.
+func (m *AtomModule) DefineFunc(name string, paramc int, callable func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int)) *AtomModule {
+	return m.Define(name, NewAtomGenericValue(
+		AtomTypeNativeFunc,
+		NewNativeFunc(name, paramc, callable),
+	))
+}
+
+// Bind adds a Go function to the module, see Bind.
+func (m *AtomModule) Bind(name string, fn any) *AtomModule {
+	return m.Define(name, Bind(name, fn))
+}
+
+func builtinModules() []*AtomModule {
+	return []*AtomModule{
+		{Name: "std", Values: EXPORT_STD},
+		{Name: "object", Values: EXPORT_OBJECT},
+		{Name: "math", Values: EXPORT_MATH},
+		{Name: "path", Values: EXPORT_PATH},
+		{Name: "os", Values: EXPORT_OS},
+		{Name: "file", Values: EXPORT_FILE},
+		{Name: "string", Values: EXPORT_STRING},
+		{Name: "number", Values: EXPORT_NUMBER},
+		{Name: "runtime", Values: EXPORT_RUNTIME},
+		{Name: "GinBinding", Values: EXPORT_GIN},
+	}
+}
+
+// RegisterModule makes a native module importable by scripts run on
+// this interpreter only, which gets its own copy of the values. Names
+// already registered cannot be replaced.
+func (i *AtomInterpreter) RegisterModule(module *AtomModule) error {
+	if !moduleNamePattern.MatchString(module.Name) {
+		return fmt.Errorf("invalid module name %s", module.Name)
+	}
+	if _, exists := i.Modules[module.Name]; exists {
+		return fmt.Errorf("module atom:%s is already registered", module.Name)
+	}
+	i.Modules[module.Name] = module
+	i.State.SaveNative(module.Name)
+	DefineModule(i, module.Name, module.Values)
+	return nil
+}
@@ -1,26 +1,34 @@
 package runtime
 
+import "fmt"
+
 type OpCode byte
 
 const (
 	OpMakeModule        OpCode = iota + 69 // with 4 bytes argument
 	OpLoadInt                              //
-	OpLoadNum                              //
-	OpLoadBigInt                           // with N bytes argument
-	OpLoadStr                              // with N bytes argument
+	OpLoadNum                              // with 4 bytes argument a.k.a constant index
+	OpLoadBigInt                           // with 4 bytes argument a.k.a constant index
+	OpLoadStr                              // with 4 bytes argument a.k.a constant index
 	OpLoadBool                             //
 	OpLoadNull                             //
 	OpLoadBase                             //
 	OpLoadArray                            // with 4 bytes argument
 	OpLoadObject                           // with 4 bytes argument
-	OpLoadName                             // with 4 bytes argument
-	OpLoadModule                           // With N bytes argument
+	OpLoadName                             // with 4 bytes argument a.k.a constant index
+	OpLoadLocal                            // with 4 bytes argument
+	OpLoadCell                             // with 4 bytes argument
+	OpLoadCapture                          // with 4 bytes argument
+	OpLoadModule                           // with 4 bytes argument a.k.a constant index
+	OpImportModule                         // with 4 bytes argument a.k.a constant index
 	OpLoadFunction                         // with 4 bytes argument
-	OpMakeClass                            // with 4 bytes argument
+	OpMakeClass                            // with 4 bytes argument and constant index
 	OpExtendClass                          //
 	OpMakeEnum                             // with 4 bytes argument
 	OpCallConstructor                      // with 4 bytes argument
 	OpCall                                 // with 4 bytes argument
+	OpTailCall                             // with 4 bytes argument
+	OpCallAttribute                        // with 4 bytes argument a.k.a constant index, 4 bytes argument a.k.a cache index and 4 bytes argument
 	OpAwait                                //
 	OpInc                                  //
 	OpDec                                  //
@@ -30,7 +38,8 @@ const (
 	OpPos                                  //
 	OpTypeof                               //
 	OpIndex                                //
-	OpPluckAttribute                       // with N bytes argument
+	OpPluckAttribute                       // with 4 bytes argument a.k.a constant index
+	OpLoadAttribute                        // with 4 bytes argument a.k.a constant index and 4 bytes argument a.k.a cache index
 	OpMul                                  //
 	OpDiv                                  //
 	OpMod                                  //
@@ -47,10 +56,18 @@ const (
 	OpAnd                                  //
 	OpOr                                   //
 	OpXor                                  //
-	OpStoreModule                          // with N bytes argument
+	OpStoreModule                          // with 4 bytes argument a.k.a constant index
+	OpInitName                             // with 4 bytes argument a.k.a constant index
+	OpStoreName                            // with 4 bytes argument a.k.a constant index
 	OpInitLocal                            // with 4 bytes argument
 	OpStoreLocal                           // with 4 bytes argument
+	OpInitCell                             // with 4 bytes argument
+	OpStoreCell                            // with 4 bytes argument
+	OpStoreCapture                         // with 4 bytes argument
+	OpIncLocal                             // with 4 bytes argument
+	OpDecLocal                             // with 4 bytes argument
 	OpSetIndex                             //
+	OpStoreAttribute                       // with 4 bytes argument a.k.a constant index and 4 bytes argument a.k.a cache index
 	OpJumpIfFalseOrPop                     // with 4 bytes argument a.k.a jump offset
 	OpJumpIfTrueOrPop                      // with 4 bytes argument a.k.a jump offset
 	OpPopJumpIfFalse                       // with 4 bytes argument a.k.a jump offset
@@ -59,15 +76,186 @@ const (
 	OpPopJumpIfNotError                    // with 4 bytes argument a.k.a jump offset
 	OpJump                                 // with 4 bytes argument a.k.a jump offset
 	OpAbsoluteJump                         // with 4 bytes argument a.k.a jump offset
+	OpCmpLtJumpIfFalse                     // with 4 bytes argument a.k.a jump offset
+	OpCmpLteJumpIfFalse                    // with 4 bytes argument a.k.a jump offset
+	OpCmpGtJumpIfFalse                     // with 4 bytes argument a.k.a jump offset
+	OpCmpGteJumpIfFalse                    // with 4 bytes argument a.k.a jump offset
+	OpCmpEqJumpIfFalse                     // with 4 bytes argument a.k.a jump offset
+	OpCmpNeJumpIfFalse                     // with 4 bytes argument a.k.a jump offset
 	OpDupTop                               //
 	OpDupTop2                              //
 	OpNoOp                                 //
 	OpPopTop                               //
-	OpEnterBlock                           //
-	OpExitBlock                            //
 	OpRot2                                 //
 	OpRot3                                 //
 	OpRot4                                 //
 	OpReturn                               //
 	// max 255
 )
+
+// OperandSize is the number of bytes following op in AtomCode.Code.
+func OperandSize(op OpCode) int {
+	switch op {
+	case OpCallAttribute:
+		return 12
+	case OpMakeClass,
+		OpLoadAttribute,
+		OpStoreAttribute:
+		return 8
+	case OpMakeModule,
+		OpLoadInt,
+		OpLoadNum,
+		OpLoadBigInt,
+		OpLoadStr,
+		OpLoadBool,
+		OpLoadArray,
+		OpLoadObject,
+		OpLoadName,
+		OpLoadLocal,
+		OpLoadCell,
+		OpLoadCapture,
+		OpLoadModule,
+		OpImportModule,
+		OpLoadFunction,
+		OpMakeEnum,
+		OpCallConstructor,
+		OpCall,
+		OpTailCall,
+		OpPluckAttribute,
+		OpStoreModule,
+		OpInitName,
+		OpStoreName,
+		OpInitLocal,
+		OpStoreLocal,
+		OpInitCell,
+		OpStoreCell,
+		OpStoreCapture,
+		OpIncLocal,
+		OpDecLocal:
+		return 4
+	}
+	if IsJump(op) {
+		return 4
+	}
+	return 0
+}
+
+// IsJump reports whether op takes a jump address as operand.
+func IsJump(op OpCode) bool {
+	switch op {
+	case OpJumpIfFalseOrPop,
+		OpJumpIfTrueOrPop,
+		OpPopJumpIfFalse,
+		OpPopJumpIfTrue,
+		OpPeekJumpIfEqual,
+		OpPopJumpIfNotError,
+		OpJump,
+		OpAbsoluteJump,
+		OpCmpLtJumpIfFalse,
+		OpCmpLteJumpIfFalse,
+		OpCmpGtJumpIfFalse,
+		OpCmpGteJumpIfFalse,
+		OpCmpEqJumpIfFalse,
+		OpCmpNeJumpIfFalse:
+		return true
+	default:
+		return false
+	}
+}
+
+// opNames are the mnemonics of the opcodes, as Decompile prints them.
+var opNames = map[OpCode]string{
+	OpMakeModule:        "MAKE_MODULE",
+	OpLoadInt:           "LOAD_INT",
+	OpLoadNum:           "LOAD_NUM",
+	OpLoadBigInt:        "LOAD_BIGINT",
+	OpLoadStr:           "LOAD_STR",
+	OpLoadBool:          "LOAD_BOOL",
+	OpLoadNull:          "LOAD_NULL",
+	OpLoadBase:          "LOAD_BASE",
+	OpLoadArray:         "LOAD_ARRAY",
+	OpLoadObject:        "LOAD_OBJECT",
+	OpLoadName:          "LOAD_NAME",
+	OpLoadLocal:         "LOAD_LOCAL",
+	OpLoadCell:          "LOAD_CELL",
+	OpLoadCapture:       "LOAD_CAPTURE",
+	OpLoadModule:        "LOAD_MODULE",
+	OpImportModule:      "IMPORT_MODULE",
+	OpLoadFunction:      "LOAD_FUNCTION",
+	OpMakeClass:         "MAKE_CLASS",
+	OpExtendClass:       "EXTEND_CLASS",
+	OpMakeEnum:          "MAKE_ENUM",
+	OpCallConstructor:   "CALL_CONSTRUCTOR",
+	OpCall:              "CALL",
+	OpTailCall:          "TAIL_CALL",
+	OpCallAttribute:     "CALL_ATTRIBUTE",
+	OpAwait:             "AWAIT",
+	OpInc:               "INC",
+	OpDec:               "DEC",
+	OpBitNot:            "BIT_NOT",
+	OpNot:               "NOT",
+	OpNeg:               "NEG",
+	OpPos:               "POS",
+	OpTypeof:            "TYPEOF",
+	OpIndex:             "INDEX",
+	OpPluckAttribute:    "PLUCK_ATTRIBUTE",
+	OpLoadAttribute:     "LOAD_ATTRIBUTE",
+	OpMul:               "MUL",
+	OpDiv:               "DIV",
+	OpMod:               "MOD",
+	OpAdd:               "ADD",
+	OpSub:               "SUB",
+	OpShl:               "SHL",
+	OpShr:               "SHR",
+	OpCmpLt:             "CMP_LT",
+	OpCmpLte:            "CMP_LTE",
+	OpCmpGt:             "CMP_GT",
+	OpCmpGte:            "CMP_GTE",
+	OpCmpEq:             "CMP_EQ",
+	OpCmpNe:             "CMP_NE",
+	OpAnd:               "AND",
+	OpOr:                "OR",
+	OpXor:               "XOR",
+	OpStoreModule:       "STORE_MODULE",
+	OpInitName:          "INIT_NAME",
+	OpStoreName:         "STORE_NAME",
+	OpInitLocal:         "INIT_LOCAL",
+	OpStoreLocal:        "STORE_LOCAL",
+	OpInitCell:          "INIT_CELL",
+	OpStoreCell:         "STORE_CELL",
+	OpStoreCapture:      "STORE_CAPTURE",
+	OpIncLocal:          "INC_LOCAL",
+	OpDecLocal:          "DEC_LOCAL",
+	OpSetIndex:          "SET_INDEX",
+	OpStoreAttribute:    "STORE_ATTRIBUTE",
+	OpJumpIfFalseOrPop:  "JUMP_IF_FALSE_OR_POP",
+	OpJumpIfTrueOrPop:   "JUMP_IF_TRUE_OR_POP",
+	OpPopJumpIfFalse:    "POP_JUMP_IF_FALSE",
+	OpPopJumpIfTrue:     "POP_JUMP_IF_TRUE",
+	OpPeekJumpIfEqual:   "PEEK_JUMP_IF_EQUAL",
+	OpPopJumpIfNotError: "POP_JUMP_IF_NOT_ERROR",
+	OpJump:              "JUMP",
+	OpAbsoluteJump:      "ABSOLUTE_JUMP",
+	OpCmpLtJumpIfFalse:  "CMP_LT_JUMP_IF_FALSE",
+	OpCmpLteJumpIfFalse: "CMP_LTE_JUMP_IF_FALSE",
+	OpCmpGtJumpIfFalse:  "CMP_GT_JUMP_IF_FALSE",
+	OpCmpGteJumpIfFalse: "CMP_GTE_JUMP_IF_FALSE",
+	OpCmpEqJumpIfFalse:  "CMP_EQ_JUMP_IF_FALSE",
+	OpCmpNeJumpIfFalse:  "CMP_NE_JUMP_IF_FALSE",
+	OpDupTop:            "DUP_TOP",
+	OpDupTop2:           "DUP_TOP2",
+	OpNoOp:              "NO_OP",
+	OpPopTop:            "POP_TOP",
+	OpRot2:              "ROT2",
+	OpRot3:              "ROT3",
+	OpRot4:              "ROT4",
+	OpReturn:            "RETURN",
+}
+
+// OpName returns the mnemonic of op.
+func OpName(op OpCode) string {
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN_OPCODE(%d)", op)
+}
@@ -3,7 +3,7 @@ package runtime
 import (
 	"fmt"
 	"math"
-	"reflect"
+	"strings"
 )
 
 func DoMakeModule(interpreter *AtomInterpreter, frame *AtomCallFrame, size int) {
@@ -43,12 +43,11 @@ func DoLoadNull(interpreter *AtomInterpreter, frame *AtomCallFrame) {
 	frame.Stack.Push(interpreter.State.NullValue)
 }
 
-func DoLoadBase(interpreter *AtomInterpreter, frame *AtomCallFrame) {
+func DoLoadBase(interpreter *AtomInterpreter, frame *AtomCallFrame, self *AtomValue) {
 	// Get base from the "self"
-	self := frame.Env.Get("self")
-	if self == nil {
+	if !CheckType(self, AtomTypeClassInstance) {
 		frame.Stack.Push(NewAtomValueError(
-			FormatError(frame, "self not defined, cannot get base"),
+			FormatError(frame, "self is not a class instance, cannot get base"),
 		))
 		return
 	}
@@ -86,7 +85,7 @@ func DoLoadObject(frame *AtomCallFrame, size int) {
 }
 
 func DoLoadName(frame *AtomCallFrame, index string) {
-	// Local?
+	// Global?
 	if frame.Env.Has(index) {
 		frame.Stack.Push(frame.Env.Get(index))
 		return
@@ -96,16 +95,80 @@ func DoLoadName(frame *AtomCallFrame, index string) {
 	))
 }
 
+func moduleNotFound(interpreter *AtomInterpreter, name string) string {
+	if _, registered := interpreter.Modules[name]; registered {
+		return fmt.Sprintf("module %s is not allowed", name)
+	}
+	return fmt.Sprintf("module %s not found", name)
+}
+
 func DoLoadModule(interpreter *AtomInterpreter, frame *AtomCallFrame, name string) {
 	module := interpreter.ModuleTable[name]
 	if module == nil {
-		message := FormatError(frame, fmt.Sprintf("module %s not found", name))
-		frame.Stack.Push(NewAtomValueError(message))
+		// Static imports cannot catch, fail before the names are plucked
+		std_throw_error(frame, NewAtomValueError(moduleNotFound(interpreter, name)))
 		return
 	}
 	frame.Stack.Push(module)
 }
 
+func DoImportModule(interpreter *AtomInterpreter, frame *AtomCallFrame, file string, path *AtomValue) {
+	if !CheckType(path, AtomTypeStr) {
+		message := FormatError(frame, fmt.Sprintf("import path must be a string, got %s", GetTypeString(path)))
+		frame.Stack.Push(NewAtomValueError(message))
+		return
+	}
+
+	resolve := func(module *AtomValue) {
+		frame.Stack.Push(NewAtomGenericValue(
+			AtomTypePromise,
+			NewAtomPromise(PromiseStateFulfilled, module),
+		))
+	}
+
+	// Builtin modules are always loaded
+	if name, found := strings.CutPrefix(path.Str, "atom:"); found {
+		module := interpreter.ModuleTable[name]
+		if module == nil {
+			message := FormatError(frame, moduleNotFound(interpreter, name))
+			frame.Stack.Push(NewAtomValueError(message))
+			return
+		}
+		resolve(module)
+		return
+	}
+
+	if interpreter.State.Loader == nil {
+		message := FormatError(frame, "dynamic import is not supported")
+		frame.Stack.Push(NewAtomValueError(message))
+		return
+	}
+
+	name, offset, err := interpreter.State.Loader(interpreter.State, file, path.Str)
+	if err != nil {
+		message := FormatError(frame, err.Error())
+		frame.Stack.Push(NewAtomValueError(message))
+		return
+	}
+
+	// Cached, the module function already ran once
+	if module := interpreter.ModuleTable[name]; module != nil {
+		resolve(module)
+		return
+	}
+
+	if offset < 0 {
+		message := FormatError(frame, fmt.Sprintf("module %s is not loaded yet", name))
+		frame.Stack.Push(NewAtomValueError(message))
+		return
+	}
+
+	DoLoadFunction(interpreter, frame, offset)
+	DoCall(interpreter, frame, frame.Stack.Pop(), 0)
+	DoStoreModule(interpreter, frame, name)
+	resolve(interpreter.ModuleTable[name])
+}
+
 func DoLoadFunction(interpreter *AtomInterpreter, frame *AtomCallFrame, offset int) {
 	// Consider everything in the function table is a closure
 	templateFn := interpreter.State.FunctionTable.Get(offset)
@@ -119,8 +182,27 @@ func DoLoadFunction(interpreter *AtomInterpreter, frame *AtomCallFrame, offset i
 	newCode := NewAtomCode(templateFile, templateName, templateAsync, templateArgc)
 	newCode.Line = templateLocals
 	newCode.Code = templateCode.Code
+	newCode.Constants = templateCode.Constants
+	newCode.Caches = templateCode.Caches
+	newCode.tier = templateCode.tier
+	newCode.Global = templateCode.Global
+	newCode.Locals = templateCode.Locals
+	newCode.Symbols = templateCode.Symbols
+	newCode.Captures = templateCode.Captures
 	newCode.Capture = frame.Env
 
+	// Only the cells the closure uses
+	if len(templateCode.Captures) > 0 {
+		newCode.Cells = make([]*AtomCell, len(templateCode.Captures))
+		for index, capture := range templateCode.Captures {
+			if capture.Local {
+				newCode.Cells[index] = frame.Cells[capture.Index]
+			} else {
+				newCode.Cells[index] = frame.Fn.Obj.(*AtomCode).Cells[capture.Index]
+			}
+		}
+	}
+
 	fn := NewAtomGenericValue(AtomTypeFunc, newCode)
 	frame.Stack.Push(fn)
 }
@@ -151,6 +233,7 @@ func DoMakeClass(interpreter *AtomInterpreter, frame *AtomCallFrame, name string
 func DoExtendClass(cls *AtomValue, ext *AtomValue) {
 	clsValue := cls.Obj.(*AtomClass)
 	clsValue.Base = ext
+	classVersion.Add(1)
 }
 
 func DoMakeEnum(frame *AtomCallFrame, size int) {
@@ -178,11 +261,24 @@ func DoMakeEnum(frame *AtomCallFrame, size int) {
 }
 
 func DoCallConstructor(interpreter *AtomInterpreter, frame *AtomCallFrame, cls *AtomValue, argc int) {
+	if callee := EnterConstructor(interpreter, frame, cls, argc); callee != nil {
+		interpreter.ExecuteFrame(callee)
+		// The initializer ran as the base frame, its result is replaced here
+		frame.Stack.Pop()
+		frame.Stack.Push(callee.Instance)
+	}
+}
+
+// EnterConstructor creates an instance of cls and runs a native
+// initializer, for an Atom initializer it returns the frame to run like
+// EnterCall. The caller receives the instance instead of the result of
+// that frame, see AtomCallFrame.Instance.
+func EnterConstructor(interpreter *AtomInterpreter, frame *AtomCallFrame, cls *AtomValue, argc int) *AtomCallFrame {
 	if !CheckType(cls, AtomTypeClass) {
 		CleanupStack(frame, argc)
 		message := FormatError(frame, GetTypeString(cls)+" is not a constructor")
 		frame.Stack.Push(NewAtomValueError(message))
-		return
+		return nil
 	}
 
 	atomClass := cls.Obj.(*AtomClass)
@@ -190,10 +286,7 @@ func DoCallConstructor(interpreter *AtomInterpreter, frame *AtomCallFrame, cls *
 	// Create this
 	this := NewAtomGenericValue(
 		AtomTypeClassInstance,
-		NewAtomClassInstance(cls, NewAtomGenericValue(
-			AtomTypeObj,
-			NewAtomObject(map[string]*AtomValue{}),
-		)),
+		NewAtomClassInstance(cls),
 	)
 
 	// Walk up the inheritance chain to collect all initializers
@@ -217,15 +310,66 @@ func DoCallConstructor(interpreter *AtomInterpreter, frame *AtomCallFrame, cls *
 		frame.Stack.Push(
 			this,
 		)
+		interpreter.allocateValue(this)
+		return nil
+	}
+
+	// Call the most derived initializer (last in the slice)
+	// The inheritance chain should be handled by the language design,
+	// not by calling multiple initializers
+	fn := initializers[0]
+	InsertThis(frame, argc, this)
+	argc++
+
+	if CheckType(fn, AtomTypeFunc) {
+		code := fn.Obj.(*AtomCode)
+		if argc != code.Argc {
+			CleanupStack(frame, argc)
+			message := FormatError(frame, fmt.Sprintf("Error: argument count mismatch, expected %d, got %d", code.Argc, argc))
+			frame.Stack.Push(NewAtomValueError(message))
+			return nil
+		}
+
+		newFrame := newFunctionFrame(interpreter, frame, fn, argc)
+		if newFrame == nil {
+			return nil
+		}
+		// Fields stored by the initializer are counted as they grow
+		interpreter.allocateValue(this)
+		newFrame.Instance = this
+		return newFrame
+
+	} else if CheckType(fn, AtomTypeNativeFunc) {
+		nativeFunc := fn.Obj.(*AtomNativeFunc)
+		if nativeFunc.Paramc != argc && nativeFunc.Paramc != Variadict {
+			CleanupStack(frame, argc)
+			message := FormatError(frame, "Error: argument count mismatch")
+			frame.Stack.Push(NewAtomValueError(message))
+			return nil
+		}
+
+		// Native constructor emits their own "this"
+		nativeFunc.Callable(interpreter, frame, argc)
+		interpreter.allocateValue(frame.Stack.Peek())
+
 	} else {
-		// Call the most derived initializer (last in the slice)
-		// The inheritance chain should be handled by the language design,
-		// not by calling multiple initializers
-		DoCallInit(interpreter, frame, initializers[0], this, 1+argc)
+		CleanupStack(frame, argc)
+		message := FormatError(frame, fmt.Sprintf("Error: %s is not a function", GetTypeString(fn)))
+		frame.Stack.Push(NewAtomValueError(message))
 	}
+	return nil
 }
 
 func DoCall(interpreter *AtomInterpreter, frame *AtomCallFrame, fn *AtomValue, argc int) {
+	if callee := EnterCall(interpreter, frame, fn, argc); callee != nil {
+		interpreter.ExecuteFrame(callee)
+	}
+}
+
+// EnterCall runs calls to native functions, for Atom functions it
+// returns the frame of the callee so the dispatch loop runs it without
+// growing the Go stack. It returns nil when there is nothing left to run.
+func EnterCall(interpreter *AtomInterpreter, frame *AtomCallFrame, fn *AtomValue, argc int) *AtomCallFrame {
 	if CheckType(fn, AtomTypeMethod) {
 		method := fn.Obj.(*AtomMethod)
 		/*
@@ -242,53 +386,13 @@ func DoCall(interpreter *AtomInterpreter, frame *AtomCallFrame, fn *AtomValue, a
 				  this
 			    ]
 		*/
-
+		InsertThis(frame, argc, method.This)
 		argc++
-
-		tmpStack := make([]*AtomValue, argc)
-		tmpStack[0] = method.This
-		for i := 1; i < argc; i++ {
-			tmpStack[i] = frame.Stack.GetOffset(argc, i)
-		}
-		for i := 0; i < argc-1; i++ {
-			frame.Stack.Pop()
-		}
-		for _, item := range tmpStack {
-			frame.Stack.Push(item)
-		}
-
 		fn = method.Fn
 
 	} else if CheckType(fn, AtomTypeNativeMethod) {
-		method := fn.Obj.(*AtomNativeMethod)
-		/*
-			    [
-			      arg1, <- top
-				  arg2,
-				  ...
-			    ]
-				  to
-				[
-			      arg1, <- top
-				  arg2,
-				  ...,
-				  this
-			    ]
-		*/
-
+		InsertThis(frame, argc, fn.Obj.(*AtomNativeMethod).This)
 		argc++
-
-		tmpStack := make([]*AtomValue, argc)
-		tmpStack[0] = method.This
-		for i := 1; i < argc; i++ {
-			tmpStack[i] = frame.Stack.GetOffset(argc, i)
-		}
-		for i := 0; i < argc-1; i++ {
-			frame.Stack.Pop()
-		}
-		for _, item := range tmpStack {
-			frame.Stack.Push(item)
-		}
 	}
 
 	if CheckType(fn, AtomTypeFunc) {
@@ -297,17 +401,12 @@ func DoCall(interpreter *AtomInterpreter, frame *AtomCallFrame, fn *AtomValue, a
 			CleanupStack(frame, argc)
 			message := FormatError(frame, fmt.Sprintf("Error: argument count mismatch, expected %d, got %d", code.Argc, argc))
 			frame.Stack.Push(NewAtomValueError(message))
-			return
+			return nil
 		}
 
-		// Create a frame for the function
-		newFrame := NewAtomCallFrame(frame, fn, 0)
-		newFrame.Stack.Copy(frame.Stack, argc)
-		CleanupStack(frame, argc)
-		interpreter.ExecuteFrame(newFrame)
-
 		// For async functions, the frame will push a promise to the stack
 		// For non-async functions, the frame will push the return value to the stack
+		return newFunctionFrame(interpreter, frame, fn, argc)
 
 	} else if CheckType(fn, AtomTypeNativeFunc) {
 		nativeFunc := fn.Obj.(*AtomNativeFunc)
@@ -315,10 +414,11 @@ func DoCall(interpreter *AtomInterpreter, frame *AtomCallFrame, fn *AtomValue, a
 			CleanupStack(frame, argc)
 			message := FormatError(frame, fmt.Sprintf("Error: argument count mismatch, expected %d, got %d", nativeFunc.Paramc, argc))
 			frame.Stack.Push(NewAtomValueError(message))
-			return
+			return nil
 		}
 
 		nativeFunc.Callable(interpreter, frame, argc)
+		interpreter.allocateValue(frame.Stack.Peek())
 
 	} else if CheckType(fn, AtomTypeNativeMethod) {
 		nativeMethod := fn.Obj.(*AtomNativeMethod)
@@ -326,70 +426,39 @@ func DoCall(interpreter *AtomInterpreter, frame *AtomCallFrame, fn *AtomValue, a
 			CleanupStack(frame, argc)
 			message := FormatError(frame, fmt.Sprintf("Error: argument count mismatch, expected %d, got %d", nativeMethod.Paramc, argc))
 			frame.Stack.Push(NewAtomValueError(message))
-			return
+			return nil
 		}
+		// Methods may grow their receiver, an array push for instance
+		before := SizeOf(nativeMethod.This)
 		nativeMethod.Callable(interpreter, frame, argc)
+		interpreter.allocateGrowth(nativeMethod.This, before)
+		if result := frame.Stack.Peek(); result != nativeMethod.This {
+			interpreter.allocateValue(result)
+		}
 
 	} else {
 		CleanupStack(frame, argc)
 		message := FormatError(frame, fmt.Sprintf("Error: %s is not a function", GetTypeString(fn)))
 		frame.Stack.Push(NewAtomValueError(message))
 	}
+	return nil
 }
 
-func DoCallInit(interpreter *AtomInterpreter, frame *AtomCallFrame, fn *AtomValue, this *AtomValue, argc int) {
-	tmpStack := make([]*AtomValue, argc)
-	tmpStack[0] = this
-	for i := 1; i < argc; i++ {
-		tmpStack[i] = frame.Stack.GetOffset(argc, i)
-	}
-	for i := 0; i < argc-1; i++ {
-		frame.Stack.Pop()
-	}
-	for _, item := range tmpStack {
-		frame.Stack.Push(item)
-	}
-
-	if CheckType(fn, AtomTypeFunc) {
-		code := fn.Obj.(*AtomCode)
-		if argc != code.Argc {
-			CleanupStack(frame, argc)
-			message := FormatError(frame, fmt.Sprintf("Error: argument count mismatch, expected %d, got %d", code.Argc, argc))
-			frame.Stack.Push(NewAtomValueError(message))
-			return
-		}
-
-		newFrame := NewAtomCallFrame(frame, fn, 0)
-		newFrame.Stack.Copy(frame.Stack, argc)
-		CleanupStack(frame, argc)
-		interpreter.ExecuteFrame(newFrame)
-
-		// Pop return
-		frame.Stack.Pop()
-		// Push this
-		frame.Stack.Push(this)
-
-	} else if CheckType(fn, AtomTypeNativeFunc) {
-		nativeFunc := fn.Obj.(*AtomNativeFunc)
-		if nativeFunc.Paramc != argc && nativeFunc.Paramc != Variadict {
-			CleanupStack(frame, argc)
-			message := FormatError(frame, "Error: argument count mismatch")
-			frame.Stack.Push(NewAtomValueError(message))
-			return
-		}
-
-		nativeFunc.Callable(interpreter, frame, argc)
-
-		// Native constructor emits their own "this"
-		// frame.Stack.Pop()
-		// Push this
-		// frame.Stack.Push(this)
-
-	} else {
+// newFunctionFrame moves the arguments of a call to a new frame, it
+// pushes an error instead when the maximum call depth is exceeded.
+func newFunctionFrame(interpreter *AtomInterpreter, frame *AtomCallFrame, fn *AtomValue, argc int) *AtomCallFrame {
+	if interpreter.MaxCallDepth > 0 && frame.Depth >= interpreter.MaxCallDepth {
 		CleanupStack(frame, argc)
-		message := FormatError(frame, fmt.Sprintf("Error: %s is not a function", GetTypeString(fn)))
+		message := FormatError(frame, "maximum call stack size exceeded"+CallTrace(frame, 10))
 		frame.Stack.Push(NewAtomValueError(message))
+		return nil
 	}
+
+	interpreter.warm(fn.Obj.(*AtomCode))
+	newFrame := NewAtomCallFrame(frame, fn, 0)
+	newFrame.Stack.Copy(frame.Stack, argc)
+	CleanupStack(frame, argc)
+	return newFrame
 }
 
 func DoBitNot(interpreter *AtomInterpreter, frame *AtomCallFrame, val *AtomValue) {
@@ -471,15 +540,15 @@ func DoIndex(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue,
 			return
 		}
 
-		r := []rune(obj.Str)
 		indexValue := CoerceToLong(index)
-		if indexValue < 0 || indexValue >= int64(len(r)) {
+		char := StrAt(obj, int(indexValue))
+		if char == nil || int64(int(indexValue)) != indexValue {
 			message := FormatError(frame, fmt.Sprintf("index out of bounds: %d", indexValue))
 			frame.Stack.Push(NewAtomValueError(message))
 			return
 		}
 
-		frame.Stack.Push(NewAtomValueStr(string(r[indexValue])))
+		frame.Stack.Push(char)
 		return
 
 	} else if CheckType(obj, AtomTypeArray) {
@@ -522,18 +591,9 @@ func DoIndex(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue,
 		return
 
 	} else if CheckType(obj, AtomTypeClass) {
-		class := obj.Obj.(*AtomClass)
-
-		for class != nil {
-			if value := class.Proto.Obj.(*AtomObject).Get(index.String()); value != nil {
-				frame.Stack.Push(value)
-				return
-			}
-			if class.Base != nil {
-				class = class.Base.Obj.(*AtomClass)
-			} else {
-				break
-			}
+		if value := findClassAttribute(obj.Obj.(*AtomClass), index.String()); value != nil {
+			frame.Stack.Push(value)
+			return
 		}
 
 		frame.Stack.Push(interpreter.State.NullValue)
@@ -541,40 +601,17 @@ func DoIndex(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue,
 
 	} else if CheckType(obj, AtomTypeClassInstance) {
 		classInstance := obj.Obj.(*AtomClassInstance)
-		property := classInstance.Property
 
 		// Direct property?
-		if reflect.TypeOf(property.Obj) == reflect.TypeOf(&AtomObject{}) && property.Obj.(*AtomObject).Get(index.String()) != nil {
-			frame.Stack.Push(property.Obj.(*AtomObject).Get(index.String()))
+		if field := classInstance.Get(index.String()); field != nil {
+			frame.Stack.Push(field)
 			return
 		}
 
 		// Is prototype?
-		current := classInstance.Prototype.Obj.(*AtomClass)
-		for current != nil {
-			// Class direct prototype?
-			if attribute := current.Proto.Obj.(*AtomObject).Get(index.String()); attribute != nil {
-				if CheckType(attribute, AtomTypeFunc) {
-					frame.Stack.Push(NewAtomGenericValue(
-						AtomTypeMethod,
-						NewAtomMethod(obj, attribute),
-					))
-					return
-				} else if CheckType(attribute, AtomTypeNativeFunc) {
-					nativeFunc := attribute.Obj.(*AtomNativeFunc)
-					frame.Stack.Push(NewAtomGenericValue(
-						AtomTypeNativeMethod,
-						NewAtomNativeMethod(nativeFunc.Name, nativeFunc.Paramc, obj, nativeFunc.Callable),
-					))
-					return
-				}
-				frame.Stack.Push(attribute)
-				return
-			}
-			if current.Base == nil {
-				break
-			}
-			current = current.Base.Obj.(*AtomClass)
+		if attribute := findClassAttribute(classInstance.Prototype.Obj.(*AtomClass), index.String()); attribute != nil {
+			frame.Stack.Push(bindAttribute(obj, attribute))
+			return
 		}
 		frame.Stack.Push(interpreter.State.NullValue)
 		return
@@ -605,6 +642,132 @@ func DoIndex(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue,
 	}
 }
 
+// findClassAttribute looks name up in class and then in its bases.
+func findClassAttribute(class *AtomClass, name string) *AtomValue {
+	for class != nil {
+		if attribute := class.Proto.Obj.(*AtomObject).Get(name); attribute != nil {
+			return attribute
+		}
+		if class.Base == nil {
+			break
+		}
+		class = class.Base.Obj.(*AtomClass)
+	}
+	return nil
+}
+
+// bindAttribute turns functions of the class of obj into its methods.
+func bindAttribute(obj *AtomValue, attribute *AtomValue) *AtomValue {
+	if CheckType(attribute, AtomTypeFunc) {
+		return NewAtomGenericValue(
+			AtomTypeMethod,
+			NewAtomMethod(obj, attribute),
+		)
+	} else if CheckType(attribute, AtomTypeNativeFunc) {
+		nativeFunc := attribute.Obj.(*AtomNativeFunc)
+		return NewAtomGenericValue(
+			AtomTypeNativeMethod,
+			NewAtomNativeMethod(nativeFunc.Name, nativeFunc.Paramc, obj, nativeFunc.Callable),
+		)
+	}
+	return attribute
+}
+
+// lookupAttribute resolves name on instance through the inline cache,
+// it also reports whether the attribute comes from the class.
+func lookupAttribute(instance *AtomClassInstance, name string, cache *AtomInlineCache) (*AtomValue, bool) {
+	if cache.Shape == instance.Shape {
+		if cache.Slot >= 0 {
+			return instance.Fields[cache.Slot], false
+		}
+		if cache.Version == classVersion.Load() {
+			return cache.Value, true
+		}
+	}
+
+	cache.Shape = instance.Shape
+	cache.Next = nil
+	cache.Value = nil
+	if cache.Slot = instance.Shape.Slot(name); cache.Slot >= 0 {
+		return instance.Fields[cache.Slot], false
+	}
+	cache.Version = classVersion.Load()
+	cache.Value = findClassAttribute(instance.Prototype.Obj.(*AtomClass), name)
+	return cache.Value, true
+}
+
+// DoLoadAttribute pushes the attribute name of obj, attributes of class
+// instances are looked up through the inline cache of the instruction.
+func DoLoadAttribute(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue, name *AtomValue, cache *AtomInlineCache) {
+	if !CheckType(obj, AtomTypeClassInstance) {
+		DoIndex(interpreter, frame, obj, name)
+		return
+	}
+
+	attribute, class := lookupAttribute(obj.Obj.(*AtomClassInstance), name.Str, cache)
+	if attribute == nil {
+		frame.Stack.Push(interpreter.State.NullValue)
+		return
+	}
+	if class {
+		attribute = bindAttribute(obj, attribute)
+	}
+	frame.Stack.Push(attribute)
+}
+
+// DoLoadMethod returns the callee of obj.name(...) and its argument
+// count. Functions of the class of an instance are not bound to it,
+// obj is inserted below the arguments instead.
+func DoLoadMethod(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue, name *AtomValue, cache *AtomInlineCache, argc int) (*AtomValue, int) {
+	if CheckType(obj, AtomTypeClassInstance) {
+		attribute, class := lookupAttribute(obj.Obj.(*AtomClassInstance), name.Str, cache)
+		if class && attribute != nil && (CheckType(attribute, AtomTypeFunc) || CheckType(attribute, AtomTypeNativeFunc)) {
+			InsertThis(frame, argc, obj)
+			return attribute, argc + 1
+		}
+	}
+	DoLoadAttribute(interpreter, frame, obj, name, cache)
+	return frame.Stack.Pop(), argc
+}
+
+// DoStoreAttribute pops the value of the attribute name of obj, fields
+// of class instances are stored through the inline cache of the instruction.
+func DoStoreAttribute(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue, name *AtomValue, cache *AtomInlineCache) {
+	if !CheckType(obj, AtomTypeClassInstance) {
+		DoSetIndex(interpreter, frame, obj, name)
+		return
+	}
+
+	instance := obj.Obj.(*AtomClassInstance)
+	if cache.Shape != instance.Shape || cache.Slot < 0 {
+		cache.Shape = instance.Shape
+		cache.Next = nil
+		cache.Value = nil
+		if cache.Slot = instance.Shape.Slot(name.Str); cache.Slot < 0 {
+			cache.Slot = len(instance.Fields)
+			cache.Next = instance.Shape.With(name.Str)
+		}
+	}
+
+	value := frame.Stack.Pop()
+	if cache.Next != nil {
+		instance.Shape = cache.Next
+		instance.Fields = append(instance.Fields, value)
+		return
+	}
+	instance.Fields[cache.Slot] = value
+}
+
+// InsertThis moves the argc arguments on top of the stack up to make
+// room for this below them.
+func InsertThis(frame *AtomCallFrame, argc int, this *AtomValue) {
+	frame.Stack.Push(nil)
+	stack := frame.Stack.Stack
+	top := len(stack) - 1
+	copy(stack[top-argc+1:], stack[top-argc:top])
+	stack[top-argc] = this
+}
+
 func DoPluckAttribute(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue, attribute string) {
 	if !CheckType(obj, AtomTypeObj) {
 		message := FormatError(frame, fmt.Sprintf("cannot pluck attribute type: %s", GetTypeString(obj)))
@@ -691,6 +854,11 @@ func DoDivision(frame *AtomCallFrame, val0 *AtomValue, val1 *AtomValue) {
 			frame.Stack.Push(NewAtomValueError(message))
 			return
 		}
+		// The only quotient outside of 32 bits is promoted
+		if a == math.MinInt32 && b == -1 {
+			frame.Stack.Push(NewAtomValueNum(-float64(a)))
+			return
+		}
 		result := a / b
 		frame.Stack.Push(NewAtomValueInt(int(result)))
 		return
@@ -832,15 +1000,12 @@ func DoAddition(frame *AtomCallFrame, val0 *AtomValue, val1 *AtomValue) {
 	}
 
 	// Fast path for strings
-	if CheckType(val0, AtomTypeStr) && CheckType(val1, AtomTypeStr) {
-		lhs := val0.Str
-		rhs := val1.Str
-		result := lhs + rhs
-		frame.Stack.Push(NewAtomValueStr(result))
+	if CheckType(val0, AtomTypeStr) {
+		frame.Stack.Push(ConcatStr(val0, val1.String()))
 		return
 	}
 
-	if CheckType(val0, AtomTypeStr) || CheckType(val1, AtomTypeStr) {
+	if CheckType(val1, AtomTypeStr) {
 		lhs := val0.String()
 		rhs := val1.String()
 		result := lhs + rhs
@@ -1183,6 +1348,42 @@ func DoCmpGte(interpreter *AtomInterpreter, frame *AtomCallFrame, val0 *AtomValu
 	frame.Stack.Push(interpreter.State.FalseValue)
 }
 
+// DoCompare evaluates the comparison of a compare-and-branch
+// instruction, integers skip the stack.
+func DoCompare(interpreter *AtomInterpreter, frame *AtomCallFrame, opcode OpCode, val0 *AtomValue, val1 *AtomValue) bool {
+	if CheckType(val0, AtomTypeInt) && CheckType(val1, AtomTypeInt) {
+		switch opcode {
+		case OpCmpLtJumpIfFalse:
+			return val0.I32 < val1.I32
+		case OpCmpLteJumpIfFalse:
+			return val0.I32 <= val1.I32
+		case OpCmpGtJumpIfFalse:
+			return val0.I32 > val1.I32
+		case OpCmpGteJumpIfFalse:
+			return val0.I32 >= val1.I32
+		case OpCmpEqJumpIfFalse:
+			return val0.I32 == val1.I32
+		case OpCmpNeJumpIfFalse:
+			return val0.I32 != val1.I32
+		}
+	}
+	switch opcode {
+	case OpCmpLtJumpIfFalse:
+		DoCmpLt(interpreter, frame, val0, val1)
+	case OpCmpLteJumpIfFalse:
+		DoCmpLte(interpreter, frame, val0, val1)
+	case OpCmpGtJumpIfFalse:
+		DoCmpGt(interpreter, frame, val0, val1)
+	case OpCmpGteJumpIfFalse:
+		DoCmpGte(interpreter, frame, val0, val1)
+	case OpCmpEqJumpIfFalse:
+		DoCmpEq(interpreter, frame, val0, val1)
+	case OpCmpNeJumpIfFalse:
+		DoCmpNe(interpreter, frame, val0, val1)
+	}
+	return CoerceToBool(frame.Stack.Pop())
+}
+
 func DoCmpEq(interpreter *AtomInterpreter, frame *AtomCallFrame, val0 *AtomValue, val1 *AtomValue) {
 	// Big?
 	if (CheckType(val0, AtomTypeBigInt) || CheckType(val1, AtomTypeBigInt)) && (IsNumberType(val0) && IsNumberType(val1)) {
@@ -1374,12 +1575,12 @@ func DoStoreModule(interpreter *AtomInterpreter, frame *AtomCallFrame, name stri
 	interpreter.ModuleTable[name] = module
 }
 
-func DoInitLocal(interpreter *AtomInterpreter, frame *AtomCallFrame, name string, value *AtomValue) {
+func DoInitName(interpreter *AtomInterpreter, frame *AtomCallFrame, name string, value *AtomValue) {
 	frame.Env.Put(name, value)
 }
 
-func DoStoreLocal(interpreter *AtomInterpreter, frame *AtomCallFrame, name string, value *AtomValue) {
-	// Local?
+func DoStoreName(interpreter *AtomInterpreter, frame *AtomCallFrame, name string, value *AtomValue) {
+	// Global?
 	if frame.Env.Has(name) {
 		frame.Env.Set(name, value)
 		return
@@ -1387,6 +1588,15 @@ func DoStoreLocal(interpreter *AtomInterpreter, frame *AtomCallFrame, name strin
 	panic("Not handled properly!!")
 }
 
+// DoInitCell declares a captured local, every declaration gets a new
+// cell so closures created in a loop keep their own variable.
+func DoInitCell(frame *AtomCallFrame, slot int, value *AtomValue) {
+	if frame.Cells == nil {
+		frame.Cells = make([]*AtomCell, len(frame.Locals))
+	}
+	frame.Cells[slot] = NewAtomCell(value)
+}
+
 func DoSetIndex(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomValue, index *AtomValue) {
 	if CheckType(obj, AtomTypeArray) {
 		if !IsNumberType(index) {
@@ -1431,10 +1641,10 @@ func DoSetIndex(interpreter *AtomInterpreter, frame *AtomCallFrame, obj *AtomVal
 	} else if CheckType(obj, AtomTypeClass) {
 		class := obj.Obj.(*AtomClass)
 		class.Proto.Obj.(*AtomObject).Set(index.String(), frame.Stack.Pop())
+		classVersion.Add(1)
 		return
 	} else if CheckType(obj, AtomTypeClassInstance) {
-		classInstance := obj.Obj.(*AtomClassInstance)
-		classInstance.Property.Obj.(*AtomObject).Set(index.String(), frame.Stack.Pop())
+		obj.Obj.(*AtomClassInstance).Set(index.String(), frame.Stack.Pop())
 		return
 	} else {
 		CleanupStack(frame, 2)
@@ -0,0 +1,458 @@
+package runtime
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// instruction is a decoded instruction of AtomCode.Code, jump operands
+// keep the addresses of the code being optimized.
+type instruction struct {
+	op      OpCode
+	args    []int
+	address int
+	removed bool
+}
+
+// optimizer rewrites the instructions of one AtomCode.
+type optimizer struct {
+	code         *AtomCode
+	instructions []*instruction
+	forward      map[int]int  // Address of a removed instruction to the next one
+	targets      map[int]bool // Addresses some jump lands on
+}
+
+// compareJumps maps the comparisons fused with a following OpPopJumpIfFalse.
+var compareJumps = map[OpCode]OpCode{
+	OpCmpLt:  OpCmpLtJumpIfFalse,
+	OpCmpLte: OpCmpLteJumpIfFalse,
+	OpCmpGt:  OpCmpGtJumpIfFalse,
+	OpCmpGte: OpCmpGteJumpIfFalse,
+	OpCmpEq:  OpCmpEqJumpIfFalse,
+	OpCmpNe:  OpCmpNeJumpIfFalse,
+}
+
+// Optimize rewrites the bytecode of code in place. It threads jumps
+// to jumps, resolves branches on constants and removes the code no path
+// reaches, drops redundant stack traffic and stores to locals that are
+// never read, then fuses common sequences into superinstructions.
+// Jump operands and AtomDebugLine addresses are remapped to the new code.
+func Optimize(code *AtomCode) {
+	o := &optimizer{
+		code:         code,
+		instructions: decode(code.Code),
+		forward:      map[int]int{},
+		targets:      map[int]bool{},
+	}
+	passes := []func() bool{
+		o.threadJumps,
+		o.foldBranches,
+		o.removeUnreachable,
+		o.removeDeadStores,
+		o.peephole,
+	}
+	for changed := true; changed; {
+		o.findTargets()
+		changed = false
+		for _, pass := range passes {
+			changed = pass() || changed
+			o.compact()
+		}
+	}
+	o.encode()
+}
+
+func decode(code []OpCode) []*instruction {
+	instructions := []*instruction{}
+	for pc := 0; pc < len(code); {
+		op := code[pc]
+		args := []int{}
+		for offset := 0; offset < OperandSize(op); offset += 4 {
+			args = append(args, ReadInt(code, pc+1+offset))
+		}
+		instructions = append(instructions, &instruction{
+			op:      op,
+			args:    args,
+			address: pc,
+		})
+		pc += 1 + OperandSize(op)
+	}
+	return instructions
+}
+
+// resolve follows removed instructions to the one now at address.
+func (o *optimizer) resolve(address int) int {
+	for {
+		next, removed := o.forward[address]
+		if !removed {
+			return address
+		}
+		address = next
+	}
+}
+
+func (o *optimizer) findTargets() {
+	o.targets = map[int]bool{}
+	for _, inst := range o.instructions {
+		if IsJump(inst.op) {
+			inst.args[0] = o.resolve(inst.args[0])
+			o.targets[inst.args[0]] = true
+		}
+	}
+}
+
+// indices maps the addresses of the instructions to their index.
+func (o *optimizer) indices() map[int]int {
+	indices := make(map[int]int, len(o.instructions))
+	for index, inst := range o.instructions {
+		indices[inst.address] = index
+	}
+	return indices
+}
+
+// remove marks the instructions in [start, end) of the list as removed,
+// jumps landing on them continue with the next instruction. Passes skip
+// removed instructions and compact drops them once the pass is done.
+func (o *optimizer) remove(start, end int) {
+	next := len(o.code.Code)
+	if end < len(o.instructions) {
+		next = o.instructions[end].address
+	}
+	for _, inst := range o.instructions[start:end] {
+		inst.removed = true
+		o.forward[inst.address] = next
+		if o.targets[inst.address] {
+			o.targets[next] = true
+		}
+	}
+}
+
+// compact drops the removed instructions from the list.
+func (o *optimizer) compact() {
+	o.instructions = slices.DeleteFunc(o.instructions, func(inst *instruction) bool {
+		return inst.removed
+	})
+}
+
+// isTarget reports whether a jump lands inside the sequence at
+// index, jumping to its first instruction is fine.
+func (o *optimizer) isTarget(index, length int) bool {
+	for _, inst := range o.instructions[index+1 : index+length] {
+		if o.targets[inst.address] {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the instructions at index have the given
+// opcodes and no jump lands in the middle of them.
+func (o *optimizer) match(index int, ops ...OpCode) bool {
+	if index+len(ops) > len(o.instructions) {
+		return false
+	}
+	for offset, op := range ops {
+		if o.instructions[index+offset].op != op {
+			return false
+		}
+	}
+	return !o.isTarget(index, len(ops))
+}
+
+// threadJumps retargets jumps landing on unconditional jumps and
+// removes unconditional jumps to the next instruction.
+func (o *optimizer) threadJumps() bool {
+	changed := false
+	indices := o.indices()
+	chain := []*instruction{}
+	seen := map[*instruction]bool{}
+	for _, inst := range o.instructions {
+		if !IsJump(inst.op) {
+			continue
+		}
+		// Every jump of the chain gets its final target, so later
+		// jumps into the chain do not walk it again
+		chain, target := append(chain[:0], inst), inst.args[0]
+		clear(seen)
+		seen[inst] = true
+		for {
+			index, ok := indices[target]
+			if !ok {
+				break
+			}
+			next := o.instructions[index]
+			if (next.op != OpJump && next.op != OpAbsoluteJump) || seen[next] {
+				break
+			}
+			chain = append(chain, next)
+			seen[next] = true
+			target = next.args[0]
+		}
+		for _, jump := range chain {
+			if jump.args[0] != target {
+				jump.args[0] = target
+				changed = true
+			}
+		}
+	}
+	for index, inst := range o.instructions {
+		if inst.op != OpJump && inst.op != OpAbsoluteJump {
+			continue
+		}
+		next := len(o.code.Code)
+		if index+1 < len(o.instructions) {
+			next = o.instructions[index+1].address
+		}
+		if inst.args[0] == next {
+			o.remove(index, index+1)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// constant returns the value a constant load pushes.
+func (o *optimizer) constant(inst *instruction) (*AtomValue, bool) {
+	switch inst.op {
+	case OpLoadInt:
+		return NewAtomValueInt(int(int32(inst.args[0]))), true
+	case OpLoadBool:
+		if inst.args[0] != 0 {
+			return NewAtomValueTrue(), true
+		}
+		return NewAtomValueFalse(), true
+	case OpLoadNull:
+		return NewAtomValueNull(), true
+	case OpLoadNum, OpLoadBigInt, OpLoadStr:
+		return o.code.Constants[inst.args[0]], true
+	default:
+		return nil, false
+	}
+}
+
+// foldBranches decides the conditional jumps on a constant the way the
+// interpreter would, they become unconditional jumps or are dropped.
+func (o *optimizer) foldBranches() bool {
+	changed := false
+	for index := 0; index+1 < len(o.instructions); index++ {
+		inst, next := o.instructions[index], o.instructions[index+1]
+		if inst.removed {
+			continue
+		}
+		value, ok := o.constant(inst)
+		if !ok || o.isTarget(index, 2) {
+			continue
+		}
+		switch next.op {
+		// LOAD POP_JUMP_IF_FALSE|TRUE => JUMP or nothing
+		case OpPopJumpIfFalse, OpPopJumpIfTrue:
+			if CoerceToBool(value) == (next.op == OpPopJumpIfTrue) {
+				inst.op, inst.args = OpJump, next.args
+				o.remove(index+1, index+2)
+			} else {
+				o.remove(index, index+2)
+			}
+
+		// LOAD JUMP_IF_FALSE_OR_POP|TRUE_OR_POP => LOAD JUMP or nothing
+		case OpJumpIfFalseOrPop, OpJumpIfTrueOrPop:
+			if CoerceToBool(value) == (next.op == OpJumpIfTrueOrPop) {
+				next.op = OpJump
+			} else {
+				o.remove(index, index+2)
+			}
+
+		// LOAD LOAD PEEK_JUMP_IF_EQUAL => LOAD JUMP or LOAD
+		default:
+			if index+2 >= len(o.instructions) || o.instructions[index+2].op != OpPeekJumpIfEqual || o.isTarget(index, 3) {
+				continue
+			}
+			pattern, ok := o.constant(next)
+			if !ok {
+				continue
+			}
+			if pattern.HashValue() == value.HashValue() {
+				next.op, next.args = OpJump, o.instructions[index+2].args
+				o.remove(index+2, index+3)
+			} else {
+				o.remove(index+1, index+3)
+			}
+		}
+		changed = true
+	}
+	return changed
+}
+
+// removeUnreachable drops the instructions no path from the entry
+// reaches, such as branches never taken and code after a return.
+func (o *optimizer) removeUnreachable() bool {
+	indices := o.indices()
+	reached := make([]bool, len(o.instructions))
+	pending := []int{0}
+	for len(pending) > 0 {
+		index := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if index >= len(o.instructions) || reached[index] {
+			continue
+		}
+		reached[index] = true
+		inst := o.instructions[index]
+		if IsJump(inst.op) {
+			if target, ok := indices[o.resolve(inst.args[0])]; ok {
+				pending = append(pending, target)
+			}
+		}
+		switch inst.op {
+		case OpJump, OpAbsoluteJump, OpReturn:
+		default:
+			pending = append(pending, index+1)
+		}
+	}
+
+	changed := false
+	for end := len(o.instructions); end > 0; {
+		if reached[end-1] {
+			end--
+			continue
+		}
+		start := end - 1
+		for start > 0 && !reached[start-1] {
+			start--
+		}
+		o.remove(start, end)
+		end = start
+		changed = true
+	}
+	return changed
+}
+
+// removeDeadStores turns stores to locals that are never loaded into
+// pops, captured locals live in cells and are left alone.
+func (o *optimizer) removeDeadStores() bool {
+	read := map[int]bool{}
+	for _, inst := range o.instructions {
+		switch inst.op {
+		case OpLoadLocal, OpIncLocal, OpDecLocal:
+			read[inst.args[0]] = true
+		}
+	}
+	changed := false
+	for _, inst := range o.instructions {
+		if (inst.op == OpInitLocal || inst.op == OpStoreLocal) && !read[inst.args[0]] {
+			inst.op = OpPopTop
+			inst.args = []int{}
+			changed = true
+		}
+	}
+	return changed
+}
+
+func isStore(op OpCode) bool {
+	switch op {
+	case OpStoreLocal, OpStoreCell, OpStoreCapture, OpStoreName:
+		return true
+	default:
+		return false
+	}
+}
+
+// isPush reports whether op only pushes a value, so it can be
+// dropped with the pop discarding that value. Loads of locals, cells
+// and captures are kept, reading one before it is initialized fails.
+func isPush(op OpCode) bool {
+	switch op {
+	case OpLoadInt, OpLoadNum, OpLoadBigInt, OpLoadStr, OpLoadBool, OpLoadNull,
+		OpDupTop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (o *optimizer) peephole() bool {
+	changed := false
+	for index := 0; index < len(o.instructions); index++ {
+		inst := o.instructions[index]
+		if inst.removed {
+			continue
+		}
+		switch {
+		// DUP_TOP STORE POP_TOP => STORE
+		case inst.op == OpDupTop && index+2 < len(o.instructions) &&
+			isStore(o.instructions[index+1].op) && o.instructions[index+2].op == OpPopTop &&
+			!o.isTarget(index, 3):
+			store := o.instructions[index+1]
+			inst.op, inst.args = store.op, store.args
+			o.remove(index+1, index+3)
+
+		// LOAD POP_TOP => nothing
+		case isPush(inst.op) && index+1 < len(o.instructions) &&
+			o.instructions[index+1].op == OpPopTop && !o.isTarget(index, 2):
+			o.remove(index, index+2)
+
+		// DUP_TOP ROT2 => DUP_TOP, swapping equal values
+		case o.match(index, OpDupTop, OpRot2):
+			o.remove(index+1, index+2)
+
+		// CMP POP_JUMP_IF_FALSE => CMP_JUMP_IF_FALSE
+		case compareJumps[inst.op] != 0 && o.match(index, inst.op, OpPopJumpIfFalse):
+			inst.op, inst.args = compareJumps[inst.op], o.instructions[index+1].args
+			o.remove(index+1, index+2)
+
+		// LOAD_LOCAL x LOAD_INT 1 ADD|SUB STORE_LOCAL x => INC_LOCAL|DEC_LOCAL x
+		case (o.match(index, OpLoadLocal, OpLoadInt, OpAdd, OpStoreLocal) ||
+			o.match(index, OpLoadLocal, OpLoadInt, OpSub, OpStoreLocal)) &&
+			o.instructions[index+1].args[0] == 1 && o.instructions[index+3].args[0] == inst.args[0]:
+			if o.instructions[index+2].op == OpAdd {
+				inst.op = OpIncLocal
+			} else {
+				inst.op = OpDecLocal
+			}
+			o.remove(index+1, index+4)
+
+		default:
+			continue
+		}
+		changed = true
+	}
+	return changed
+}
+
+// encode writes the instructions back with remapped addresses.
+func (o *optimizer) encode() {
+	addresses := map[int]int{}
+	pc := 0
+	for _, inst := range o.instructions {
+		addresses[inst.address] = pc
+		pc += 1 + OperandSize(inst.op)
+	}
+	addresses[len(o.code.Code)] = pc
+
+	relocate := func(address int) int {
+		return addresses[o.resolve(address)]
+	}
+
+	code := make([]OpCode, 0, pc)
+	for _, inst := range o.instructions {
+		code = append(code, inst.op)
+		for index, arg := range inst.args {
+			if index == 0 && IsJump(inst.op) {
+				arg = relocate(arg)
+			}
+			bytes := []byte{0, 0, 0, 0}
+			binary.LittleEndian.PutUint32(bytes, uint32(arg))
+			for _, b := range bytes {
+				code = append(code, OpCode(b))
+			}
+		}
+	}
+
+	lines := make([]AtomDebugLine, len(o.code.Line))
+	for index, line := range o.code.Line {
+		lines[index] = AtomDebugLine{
+			Line:    line.Line,
+			Address: relocate(line.Address),
+		}
+	}
+	o.code.Code = code
+	o.code.Line = lines
+}
@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// branchyFunction assembles a function with one if statement per
+// branch, laid out the way the compiler emits them.
+func branchyFunction(t testing.TB, branches int) *AtomCode {
+	t.Helper()
+	var source strings.Builder
+	source.WriteString(".program \"main\" argc=0 locals=0 global\n    LOAD_NULL\n    RETURN\n")
+	source.WriteString(".function 0 \"branchy\" argc=1 locals=2\n    INIT_LOCAL 0\n    LOAD_INT 0\n    INIT_LOCAL 1\n")
+	for branch := range branches {
+		fmt.Fprintf(&source, `    LOAD_LOCAL 0
+    LOAD_INT %d
+    CMP_EQ
+    POP_JUMP_IF_FALSE L_%d
+    LOAD_LOCAL 1
+    LOAD_INT %d
+    ADD
+    STORE_LOCAL 1
+    JUMP L_%d
+L_%d:
+`, branch, branch, branch, branch, branch)
+	}
+	source.WriteString("    LOAD_LOCAL 1\n    RETURN\n")
+
+	state := NewAtomState()
+	if _, err := Assemble(state, "branchy.asm", source.String()); err != nil {
+		t.Fatal(err)
+	}
+	return state.FunctionTable.Get(0).Obj.(*AtomCode)
+}
+
+func TestOptimizeLargeFunction(t *testing.T) {
+	code := branchyFunction(t, 1000)
+	Optimize(code)
+
+	// The jumps to the next instruction are dropped and the compares fused
+	for _, inst := range decode(code.Code) {
+		if inst.op == OpJump || inst.op == OpPopJumpIfFalse {
+			t.Fatalf("%s left at %d", opNames[inst.op], inst.address)
+		}
+	}
+	if err := Verify(code, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestOptimizeScalesLinearly guards against passes that scan the whole
+// function for each instruction, 4 times the code should take about
+// 4 times as long, not 16.
+func TestOptimizeScalesLinearly(t *testing.T) {
+	measure := func(branches int) time.Duration {
+		best := time.Duration(0)
+		for range 3 {
+			code := branchyFunction(t, branches)
+			start := time.Now()
+			Optimize(code)
+			if elapsed := time.Since(start); best == 0 || elapsed < best {
+				best = elapsed
+			}
+		}
+		return best
+	}
+	small, large := measure(2000), measure(8000)
+	if large > 10*small {
+		t.Fatalf("optimizing 2000 branches took %v, 8000 took %v", small, large)
+	}
+}
+
+func BenchmarkOptimize(b *testing.B) {
+	for _, branches := range []int{1000, 15000} {
+		b.Run(fmt.Sprintf("branches=%d", branches), func(b *testing.B) {
+			for b.Loop() {
+				b.StopTimer()
+				code := branchyFunction(b, branches)
+				b.StartTimer()
+				Optimize(code)
+			}
+		})
+	}
+}
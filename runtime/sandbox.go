@@ -0,0 +1,143 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// AtomSandbox restricts what untrusted scripts run by an interpreter
+// may reach. The zero value allows everything.
+type AtomSandbox struct {
+	// Modules lists the native modules scripts may import, as "math" for
+	// a whole module or "path.join" for a single function. Nil allows all.
+	Modules []string
+	// Roots lists the directories the file and path builtins may access.
+	// Nil allows any path.
+	Roots []string
+	// NoExec disables os.exec and os.exit.
+	NoExec bool
+}
+
+// Restrict applies a sandbox to the interpreter, modules already
+// defined are redefined with the denied functions replaced.
+func (i *AtomInterpreter) Restrict(sandbox AtomSandbox) error {
+	roots := make([]string, len(sandbox.Roots))
+	for index, root := range sandbox.Roots {
+		absRoot, err := resolvePath(root)
+		if err != nil {
+			return fmt.Errorf("invalid sandbox root %s: %w", root, err)
+		}
+		roots[index] = absRoot
+	}
+	sandbox.Roots = roots
+	i.State.Sandbox = &sandbox
+
+	for name := range i.ModuleTable {
+		if module, exists := i.Modules[name]; exists {
+			DefineModule(i, name, module.Values)
+		}
+	}
+	return nil
+}
+
+func (s *AtomSandbox) allowsModule(name string) bool {
+	if s.Modules == nil {
+		return true
+	}
+	for _, entry := range s.Modules {
+		if entry == name || strings.HasPrefix(entry, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AtomSandbox) allowsFunction(module string, name string) error {
+	if s.NoExec && module == "os" && (name == "exec" || name == "exit") {
+		return errors.New("process execution is disabled")
+	}
+	if s.Modules == nil || slices.Contains(s.Modules, module) || slices.Contains(s.Modules, module+"."+name) {
+		return nil
+	}
+	return errors.New("access denied")
+}
+
+// filterModule replaces the functions of a module the sandbox denies,
+// calling them raises a catchable error.
+func (s *AtomSandbox) filterModule(module string, elements map[string]*AtomValue) {
+	for name, value := range elements {
+		if !CheckType(value, AtomTypeNativeFunc) {
+			continue
+		}
+		if err := s.allowsFunction(module, name); err != nil {
+			elements[name] = deniedFunc(module+"."+name, err)
+		}
+	}
+}
+
+func deniedFunc(name string, reason error) *AtomValue {
+	return NewAtomGenericValue(
+		AtomTypeNativeFunc,
+		NewNativeFunc(name, Variadict, func(interpreter *AtomInterpreter, frame *AtomCallFrame, argc int) {
+			CleanupStack(frame, argc)
+			frame.Stack.Push(NewAtomValueError(
+				FormatError(frame, fmt.Sprintf("%s: %s", name, reason.Error())),
+			))
+		}),
+	)
+}
+
+// CheckPath returns an error when the sandbox of the interpreter does
+// not allow access to path, see AtomSandbox.CheckPath.
+func (i *AtomInterpreter) CheckPath(path string) error {
+	return i.State.Sandbox.CheckPath(path)
+}
+
+// CheckPath returns an error when path is outside of the roots. Symbolic
+// links are followed, so a link inside a root cannot point outside of it.
+// A nil sandbox allows any path.
+func (s *AtomSandbox) CheckPath(path string) error {
+	if s == nil || s.Roots == nil {
+		return nil
+	}
+	absPath, err := resolvePath(path)
+	if err == nil {
+		for _, root := range s.Roots {
+			if relative, err := filepath.Rel(root, absPath); err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("access to %s is denied", path)
+}
+
+// resolvePath makes a path absolute and resolves the symbolic links of
+// its longest existing prefix, the rest may not exist yet.
+func resolvePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	missing := []string{}
+	current := absPath
+	for {
+		if _, err := os.Lstat(current); err == nil {
+			break
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		missing = append([]string{filepath.Base(current)}, missing...)
+		current = parent
+	}
+	resolved, err := filepath.EvalSymlinks(current)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{resolved}, missing...)...), nil
+}
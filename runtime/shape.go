@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AtomShape is the hidden class of class instances, it lists the names
+// of their fields in slot order. Instances of a class that add the same
+// fields in the same order share their shapes, so a slot found for one
+// shape is valid for every instance having it.
+type AtomShape struct {
+	Names       []string
+	slots       map[string]int
+	mutex       sync.Mutex
+	transitions map[string]*AtomShape
+}
+
+func NewAtomShape() *AtomShape {
+	return &AtomShape{
+		Names:       []string{},
+		slots:       map[string]int{},
+		transitions: map[string]*AtomShape{},
+	}
+}
+
+// Slot returns the index of the field name, -1 when the shape has none.
+func (s *AtomShape) Slot(name string) int {
+	if slot, ok := s.slots[name]; ok {
+		return slot
+	}
+	return -1
+}
+
+// With returns the shape of instances adding the field name,
+// the transition is created once and shared afterwards.
+func (s *AtomShape) With(name string) *AtomShape {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if next, ok := s.transitions[name]; ok {
+		return next
+	}
+	next := &AtomShape{
+		Names:       append(append(make([]string, 0, len(s.Names)+1), s.Names...), name),
+		slots:       make(map[string]int, len(s.slots)+1),
+		transitions: map[string]*AtomShape{},
+	}
+	for field, slot := range s.slots {
+		next.slots[field] = slot
+	}
+	next.slots[name] = len(s.Names)
+	s.transitions[name] = next
+	return next
+}
+
+// classVersion changes whenever an attribute of a class is set or a
+// class gets a base, attributes cached from classes are stale then.
+var classVersion atomic.Int64
+
+// AtomInlineCache remembers how an attribute instruction resolved its
+// attribute for the last instance shape it saw.
+type AtomInlineCache struct {
+	Shape   *AtomShape
+	Slot    int        // Field slot, -1 for an attribute of the class
+	Next    *AtomShape // Shape after a store adding the field
+	Value   *AtomValue // Attribute of the class
+	Version int64      // Class version the attribute was found in
+}
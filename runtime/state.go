@@ -5,10 +5,21 @@ import (
 	"path/filepath"
 )
 
+// AtomModuleLoader compiles the module behind an import path requested
+// at runtime and returns its module name and function table offset.
+// The offset is -1 when the module was already compiled.
+type AtomModuleLoader func(state *AtomState, file string, path string) (name string, offset int, err error)
+
 type AtomState struct {
 	Path          string
 	ModuleLookup  map[string]bool
+	ExportLookup  map[string][]string
+	Natives       map[string]bool // Native modules defined by the interpreters
 	FunctionTable *AtomStack
+	Loader        AtomModuleLoader
+	Sandbox       *AtomSandbox          // Set by AtomInterpreter.Restrict, nil allows everything
+	Optimize      bool                  // Run the bytecode optimizer on compiled code
+	Strings       map[string]*AtomValue // Interned identifiers and string constants
 	NullValue     *AtomValue
 	FalseValue    *AtomValue
 	TrueValue     *AtomValue
@@ -22,7 +33,12 @@ func NewAtomState() *AtomState {
 	return &AtomState{
 		Path:          filepath.Dir(path),
 		ModuleLookup:  map[string]bool{},
+		ExportLookup:  map[string][]string{},
+		Natives:       map[string]bool{},
 		FunctionTable: NewAtomStack(),
+		Loader:        nil,
+		Optimize:      true,
+		Strings:       map[string]*AtomValue{},
 		NullValue:     NewAtomValueNull(),
 		FalseValue:    NewAtomValueFalse(),
 		TrueValue:     NewAtomValueTrue(),
@@ -37,6 +53,42 @@ func (s *AtomState) SaveModule(name string) (exists bool) {
 	return false
 }
 
+// RemoveModule forgets a module, so the next import compiles it again.
+func (s *AtomState) RemoveModule(name string) {
+	delete(s.ModuleLookup, name)
+	delete(s.ExportLookup, name)
+}
+
+// SaveNative records a native module, so imports of "atom:<name>"
+// compile.
+func (s *AtomState) SaveNative(name string) {
+	s.Natives[name] = true
+}
+
+func (s *AtomState) IsNative(name string) bool {
+	return s.Natives[name]
+}
+
+func (s *AtomState) SaveExports(name string, names []string) {
+	s.ExportLookup[name] = names
+}
+
+func (s *AtomState) Exports(name string) (names []string, exists bool) {
+	names, exists = s.ExportLookup[name]
+	return names, exists
+}
+
+// Intern returns the one string value of the state equal to value,
+// constants of every function compiled by the state share it.
+func (s *AtomState) Intern(value string) *AtomValue {
+	if interned, ok := s.Strings[value]; ok {
+		return interned
+	}
+	interned := NewAtomValueStr(value)
+	s.Strings[value] = interned
+	return interned
+}
+
 func (s *AtomState) SaveFunction(obj *AtomValue) int {
 	s.FunctionTable.Push(obj)
 	return s.FunctionTable.Len() - 1
@@ -0,0 +1,145 @@
+package runtime
+
+import (
+	"sync"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// Concatenations shorter than this are copied, longer ones share a
+// growable buffer so appending to the result does not copy it again.
+const builderThreshold = 64
+
+// atomStr is kept in AtomValue.Obj of every string, Str stays a plain
+// Go string. It is created with the value, so reading a string shared
+// by interpreters never writes to the value itself.
+type atomStr struct {
+	builder *strBuilder
+	shared  int // Leading bytes already counted for the string appended to
+	indexed sync.Once
+	runes   []int // Byte offset of every rune, nil for ASCII strings
+}
+
+// strValue allocates a string value together with its atomStr.
+type strValue struct {
+	value AtomValue
+	meta  atomStr
+}
+
+// strBuilder holds the bytes of strings built by appending, every one
+// of them is a prefix of bytes. Bytes below len(bytes) are never written
+// again, so only the string as long as bytes can append in place.
+type strBuilder struct {
+	mutex sync.Mutex
+	bytes []byte
+}
+
+// ASCII characters are preallocated, indexing a string returns them.
+var asciiChars [utf8.RuneSelf]AtomValue
+
+func init() {
+	for index := range asciiChars {
+		meta := &atomStr{}
+		asciiChars[index] = AtomValue{
+			Type: AtomTypeStr,
+			Str:  string(rune(index)),
+			Obj:  meta,
+		}
+		meta.index(asciiChars[index].Str)
+	}
+}
+
+// strMeta returns the atomStr of a string value. Values made without
+// NewAtomValueStr get a new one each time, it is never stored.
+func strMeta(value *AtomValue) *atomStr {
+	if meta, ok := value.Obj.(*atomStr); ok {
+		return meta
+	}
+	return &atomStr{}
+}
+
+// ConcatStr returns the string lhs followed by rhs. Long results are
+// appended in place to the buffer of lhs when lhs is its whole content,
+// which makes building a string piece by piece linear.
+func ConcatStr(lhs *AtomValue, rhs string) *AtomValue {
+	size := len(lhs.Str) + len(rhs)
+	if size < builderThreshold || len(rhs) == 0 {
+		return NewAtomValueStr(lhs.Str + rhs)
+	}
+
+	var builder *strBuilder
+	if meta, ok := lhs.Obj.(*atomStr); ok {
+		builder = meta.builder
+	}
+	if builder != nil {
+		builder.mutex.Lock()
+		if len(builder.bytes) != len(lhs.Str) {
+			builder.mutex.Unlock()
+			builder = nil
+		}
+	}
+	shared := len(lhs.Str)
+	if builder == nil {
+		builder = &strBuilder{bytes: make([]byte, 0, size*2)}
+		builder.mutex.Lock()
+		builder.bytes = append(builder.bytes, lhs.Str...)
+		shared = 0
+	}
+	builder.bytes = append(builder.bytes, rhs...)
+	bytes := builder.bytes
+	builder.mutex.Unlock()
+
+	result := NewAtomValueStr(unsafe.String(unsafe.SliceData(bytes), len(bytes)))
+	meta := result.Obj.(*atomStr)
+	meta.builder = builder
+	meta.shared = shared
+	return result
+}
+
+// index finds the rune offsets of the string once, interpreters
+// reading the same string wait for the first one.
+func (s *atomStr) index(str string) {
+	s.indexed.Do(func() {
+		for offset := 0; offset < len(str); offset++ {
+			if str[offset] >= utf8.RuneSelf {
+				runes := make([]int, 0, len(str))
+				for offset := range str {
+					runes = append(runes, offset)
+				}
+				s.runes = runes
+				return
+			}
+		}
+	})
+}
+
+// StrLen returns the number of runes of the string value.
+func StrLen(value *AtomValue) int {
+	meta := strMeta(value)
+	meta.index(value.Str)
+	if meta.runes == nil {
+		return len(value.Str)
+	}
+	return len(meta.runes)
+}
+
+// StrAt returns the rune at index of the string value as a string,
+// nil when index is out of bounds.
+func StrAt(value *AtomValue, index int) *AtomValue {
+	meta := strMeta(value)
+	meta.index(value.Str)
+	if meta.runes == nil {
+		if index < 0 || index >= len(value.Str) {
+			return nil
+		}
+		return &asciiChars[value.Str[index]]
+	}
+	if index < 0 || index >= len(meta.runes) {
+		return nil
+	}
+	r, _ := utf8.DecodeRuneInString(value.Str[meta.runes[index]:])
+	if r < utf8.RuneSelf {
+		return &asciiChars[r]
+	}
+	return NewAtomValueStr(string(r))
+}
@@ -0,0 +1,489 @@
+package runtime
+
+import "math/big"
+
+// atomStep runs the instruction it was compiled from and returns the
+// address of the next instruction. frame.Ip is past the opcode when it
+// starts, as in the switch of ExecuteFrame, so errors report the same line.
+type atomStep func(i *AtomInterpreter, frame *AtomCallFrame) int
+
+// atomTier counts how hot an AtomCode is and holds its threaded code
+// once it is past the threshold, closures of the code share it.
+type atomTier struct {
+	heat  int
+	steps []atomStep // Indexed by address, nil runs the instruction in the switch
+}
+
+// warm records a call or a loop iteration of code, it compiles the
+// threaded code when the code gets hot.
+func (i *AtomInterpreter) warm(code *AtomCode) {
+	tier := code.tier
+	if tier.steps != nil || i.Threshold <= 0 {
+		return
+	}
+	if tier.heat++; tier.heat >= i.Threshold {
+		tier.steps = compileSteps(code)
+	}
+}
+
+// compileSteps translates the instructions of code into steps with
+// their operands decoded and their constants bound. Instructions that
+// switch frames, such as calls, returns and await, stay in the switch.
+func compileSteps(code *AtomCode) []atomStep {
+	steps := make([]atomStep, len(code.Code))
+	for pc := 0; pc < len(code.Code); pc += 1 + OperandSize(code.Code[pc]) {
+		steps[pc] = compileStep(code, pc)
+	}
+	return steps
+}
+
+func compileStep(code *AtomCode, pc int) atomStep {
+	op := code.Code[pc]
+	next := pc + 1 + OperandSize(op)
+
+	operand := func(offset int) int {
+		return ReadInt(code.Code, pc+1+offset)
+	}
+
+	switch op {
+	case OpLoadInt:
+		value := NewAtomValueInt(operand(0))
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(value)
+			return next
+		}
+
+	case OpLoadNum:
+		value := code.Constants[operand(0)]
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(value)
+			return next
+		}
+
+	case OpLoadBigInt:
+		value := code.Constants[operand(0)].Obj.(*big.Int)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(NewAtomValueBigInt(new(big.Int).Set(value)))
+			i.allocateValue(frame.Stack.Peek())
+			return next
+		}
+
+	case OpLoadStr:
+		value := code.Constants[operand(0)].Str
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(NewAtomValueStr(value))
+			i.allocateValue(frame.Stack.Peek())
+			return next
+		}
+
+	case OpLoadBool:
+		value := operand(0) != 0
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			if value {
+				frame.Stack.Push(i.State.TrueValue)
+			} else {
+				frame.Stack.Push(i.State.FalseValue)
+			}
+			return next
+		}
+
+	case OpLoadNull:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(i.State.NullValue)
+			return next
+		}
+
+	case OpLoadArray:
+		size := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoLoadArray(frame, size)
+			i.allocateValue(frame.Stack.Peek())
+			return next
+		}
+
+	case OpLoadObject:
+		size := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoLoadObject(frame, size)
+			i.allocateValue(frame.Stack.Peek())
+			return next
+		}
+
+	case OpLoadName:
+		name := code.Constants[operand(0)].Str
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoLoadName(frame, name)
+			return next
+		}
+
+	case OpLoadLocal:
+		slot := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(frame.Locals[slot])
+			return next
+		}
+
+	case OpLoadCell:
+		slot := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(frame.Cells[slot].Value)
+			return next
+		}
+
+	case OpLoadCapture:
+		// Each closure has its own cells
+		index := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(frame.Fn.Obj.(*AtomCode).Cells[index].Value)
+			return next
+		}
+
+	case OpLoadFunction:
+		offset := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoLoadFunction(i, frame, offset)
+			return next
+		}
+
+	case OpIndex:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			idx := frame.Stack.Pop()
+			obj := frame.Stack.Pop()
+			DoIndex(i, frame, obj, idx)
+			return next
+		}
+
+	case OpLoadAttribute:
+		name := code.Constants[operand(0)]
+		cache := &code.Caches[operand(4)]
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			obj := frame.Stack.Pop()
+			DoLoadAttribute(i, frame, obj, name, cache)
+			return next
+		}
+
+	case OpStoreAttribute:
+		name := code.Constants[operand(0)]
+		cache := &code.Caches[operand(4)]
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			obj := frame.Stack.Pop()
+			before := SizeOf(obj)
+			DoStoreAttribute(i, frame, obj, name, cache)
+			i.allocateGrowth(obj, before)
+			return next
+		}
+
+	case OpSetIndex:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			idx := frame.Stack.Pop()
+			obj := frame.Stack.Pop()
+			before := SizeOf(obj)
+			DoSetIndex(i, frame, obj, idx)
+			i.allocateGrowth(obj, before)
+			return next
+		}
+
+	case OpBitNot:
+		return unaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, val *AtomValue) {
+			DoBitNot(i, frame, val)
+		})
+	case OpNot:
+		return unaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, val *AtomValue) {
+			DoNot(i, frame, val)
+		})
+	case OpNeg:
+		return unaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, val *AtomValue) {
+			DoNeg(frame, val)
+		})
+	case OpPos:
+		return unaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, val *AtomValue) {
+			DoPos(frame, val)
+		})
+	case OpInc:
+		return unaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, val *AtomValue) {
+			DoInc(frame, val)
+		})
+	case OpDec:
+		return unaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, val *AtomValue) {
+			DoDec(frame, val)
+		})
+	case OpTypeof:
+		return unaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, val *AtomValue) {
+			DoTypeof(frame, val)
+		})
+
+	case OpMul:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			rhs := frame.Stack.Pop()
+			lhs := frame.Stack.Pop()
+			DoMultiplication(frame, lhs, rhs)
+			i.allocateValue(frame.Stack.Peek())
+			return next
+		}
+	case OpAdd:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			rhs := frame.Stack.Pop()
+			lhs := frame.Stack.Pop()
+			DoAddition(frame, lhs, rhs)
+			i.allocateValue(frame.Stack.Peek())
+			return next
+		}
+	case OpShl:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			rhs := frame.Stack.Pop()
+			lhs := frame.Stack.Pop()
+			DoShiftLeft(frame, lhs, rhs)
+			i.allocateValue(frame.Stack.Peek())
+			return next
+		}
+	case OpDiv:
+		return binaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, lhs, rhs *AtomValue) {
+			DoDivision(frame, lhs, rhs)
+		})
+	case OpMod:
+		return binaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, lhs, rhs *AtomValue) {
+			DoModulus(frame, lhs, rhs)
+		})
+	case OpSub:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			rhs := frame.Stack.Pop()
+			lhs := frame.Stack.Pop()
+			DoSubtraction(frame, lhs, rhs)
+			i.allocateValue(frame.Stack.Peek())
+			return next
+		}
+	case OpShr:
+		return binaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, lhs, rhs *AtomValue) {
+			DoShiftRight(frame, lhs, rhs)
+		})
+	case OpCmpLt:
+		return binaryStep(next, DoCmpLt)
+	case OpCmpLte:
+		return binaryStep(next, DoCmpLte)
+	case OpCmpGt:
+		return binaryStep(next, DoCmpGt)
+	case OpCmpGte:
+		return binaryStep(next, DoCmpGte)
+	case OpCmpEq:
+		return binaryStep(next, DoCmpEq)
+	case OpCmpNe:
+		return binaryStep(next, DoCmpNe)
+	case OpAnd:
+		return binaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, lhs, rhs *AtomValue) {
+			DoAnd(frame, lhs, rhs)
+		})
+	case OpOr:
+		return binaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, lhs, rhs *AtomValue) {
+			DoOr(frame, lhs, rhs)
+		})
+	case OpXor:
+		return binaryStep(next, func(i *AtomInterpreter, frame *AtomCallFrame, lhs, rhs *AtomValue) {
+			DoXor(frame, lhs, rhs)
+		})
+
+	case OpInitName:
+		name := code.Constants[operand(0)].Str
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoInitName(i, frame, name, frame.Stack.Pop())
+			return next
+		}
+
+	case OpStoreName:
+		name := code.Constants[operand(0)].Str
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoStoreName(i, frame, name, frame.Stack.Pop())
+			return next
+		}
+
+	case OpInitLocal, OpStoreLocal:
+		slot := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Locals[slot] = frame.Stack.Pop()
+			return next
+		}
+
+	case OpInitCell:
+		slot := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoInitCell(frame, slot, frame.Stack.Pop())
+			return next
+		}
+
+	case OpStoreCell:
+		slot := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Cells[slot].Value = frame.Stack.Pop()
+			return next
+		}
+
+	case OpStoreCapture:
+		index := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Fn.Obj.(*AtomCode).Cells[index].Value = frame.Stack.Pop()
+			return next
+		}
+
+	case OpIncLocal:
+		slot := operand(0)
+		one := NewAtomValueInt(1)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoAddition(frame, frame.Locals[slot], one)
+			i.allocateValue(frame.Stack.Peek())
+			frame.Locals[slot] = frame.Stack.Pop()
+			return next
+		}
+
+	case OpDecLocal:
+		slot := operand(0)
+		one := NewAtomValueInt(1)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoSubtraction(frame, frame.Locals[slot], one)
+			i.allocateValue(frame.Stack.Peek())
+			frame.Locals[slot] = frame.Stack.Pop()
+			return next
+		}
+
+	case OpJumpIfFalseOrPop:
+		target := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			if !CoerceToBool(frame.Stack.Peek()) {
+				return target
+			}
+			frame.Stack.Pop()
+			return next
+		}
+
+	case OpJumpIfTrueOrPop:
+		target := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			if CoerceToBool(frame.Stack.Peek()) {
+				return target
+			}
+			frame.Stack.Pop()
+			return next
+		}
+
+	case OpPopJumpIfFalse:
+		target := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			if !CoerceToBool(frame.Stack.Pop()) {
+				return target
+			}
+			return next
+		}
+
+	case OpPopJumpIfTrue:
+		target := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			if CoerceToBool(frame.Stack.Pop()) {
+				return target
+			}
+			return next
+		}
+
+	case OpPeekJumpIfEqual:
+		target := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			rhs := frame.Stack.Pop()
+			if rhs.HashValue() == frame.Stack.Peek().HashValue() {
+				return target
+			}
+			return next
+		}
+
+	case OpPopJumpIfNotError:
+		target := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			if !CheckType(frame.Stack.Peek(), AtomTypeErr) {
+				return target
+			}
+			return next
+		}
+
+	case OpJump, OpAbsoluteJump:
+		target := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			return target
+		}
+
+	case OpCmpLtJumpIfFalse,
+		OpCmpLteJumpIfFalse,
+		OpCmpGtJumpIfFalse,
+		OpCmpGteJumpIfFalse,
+		OpCmpEqJumpIfFalse,
+		OpCmpNeJumpIfFalse:
+		target := operand(0)
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			// Past the operand like the switch
+			frame.Ip = next
+			rhs := frame.Stack.Pop()
+			lhs := frame.Stack.Pop()
+			if !DoCompare(i, frame, op, lhs, rhs) {
+				return target
+			}
+			return next
+		}
+
+	case OpDupTop:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Push(frame.Stack.Peek())
+			return next
+		}
+
+	case OpDupTop2:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			top := frame.Stack.GetN(2)
+			frame.Stack.Push(top[0])
+			frame.Stack.Push(top[1])
+			return next
+		}
+
+	case OpNoOp:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			return next
+		}
+
+	case OpPopTop:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			frame.Stack.Pop()
+			return next
+		}
+
+	case OpRot2:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoRot2(frame)
+			return next
+		}
+
+	case OpRot3:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoRot3(frame)
+			return next
+		}
+
+	case OpRot4:
+		return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+			DoRot4(frame)
+			return next
+		}
+
+	default:
+		return nil
+	}
+}
+
+func unaryStep(next int, do func(i *AtomInterpreter, frame *AtomCallFrame, val *AtomValue)) atomStep {
+	return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+		do(i, frame, frame.Stack.Pop())
+		return next
+	}
+}
+
+func binaryStep(next int, do func(i *AtomInterpreter, frame *AtomCallFrame, lhs, rhs *AtomValue)) atomStep {
+	return func(i *AtomInterpreter, frame *AtomCallFrame) int {
+		rhs := frame.Stack.Pop()
+		lhs := frame.Stack.Pop()
+		do(i, frame, lhs, rhs)
+		return next
+	}
+}
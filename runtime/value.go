@@ -68,12 +68,48 @@ func NewAtomValue(atomType AtomType) *AtomValue {
 	return obj
 }
 
+// Integers in [smallIntMin, smallIntMax] are preallocated, scalar
+// values are never modified so every interpreter can share them.
+const (
+	smallIntMin = -1024
+	smallIntMax = 16383
+)
+
+var smallInts [smallIntMax - smallIntMin + 1]AtomValue
+
+func init() {
+	for index := range smallInts {
+		smallInts[index] = AtomValue{
+			Type: AtomTypeInt,
+			I32:  int32(index + smallIntMin),
+		}
+	}
+}
+
 func NewAtomValueInt(value int) *AtomValue {
+	if value >= smallIntMin && value <= smallIntMax {
+		return &smallInts[value-smallIntMin]
+	}
 	obj := NewAtomValue(AtomTypeInt)
 	obj.I32 = int32(value)
 	return obj
 }
 
+// Booleans and null are preallocated like the small integers.
+var (
+	atomFalse = AtomValue{Type: AtomTypeBool, I32: 0}
+	atomTrue  = AtomValue{Type: AtomTypeBool, I32: 1}
+	atomNull  = AtomValue{Type: AtomTypeNull}
+)
+
+// isShared reports whether value is one of the preallocated values.
+func isShared(value *AtomValue) bool {
+	if value.Type == AtomTypeInt {
+		return value.I32 >= smallIntMin && value.I32 <= smallIntMax
+	}
+	return value == &atomFalse || value == &atomTrue || value == &atomNull
+}
+
 func NewAtomValueNum(value float64) *AtomValue {
 	obj := NewAtomValue(AtomTypeNum)
 	obj.F64 = value
@@ -87,27 +123,23 @@ func NewAtomValueBigInt(value *big.Int) *AtomValue {
 }
 
 func NewAtomValueFalse() *AtomValue {
-	obj := NewAtomValue(AtomTypeBool)
-	obj.I32 = 0
-	return obj
+	return &atomFalse
 }
 
 func NewAtomValueTrue() *AtomValue {
-	obj := NewAtomValue(AtomTypeBool)
-	obj.I32 = 1
-	return obj
+	return &atomTrue
 }
 
 func NewAtomValueStr(value string) *AtomValue {
-	obj := NewAtomValue(AtomTypeStr)
-	obj.Str = value
-	return obj
+	str := &strValue{}
+	str.value.Type = AtomTypeStr
+	str.value.Str = value
+	str.value.Obj = &str.meta
+	return &str.value
 }
 
 func NewAtomValueNull() *AtomValue {
-	obj := NewAtomValue(AtomTypeNull)
-	obj.I32 = 0
-	return obj
+	return &atomNull
 }
 
 func NewAtomValueError(message string) *AtomValue {
@@ -165,60 +197,12 @@ func (v *AtomValue) StringWithVisited(visited map[uintptr]bool) string {
 		})
 
 	case AtomTypeClassInstance:
-		// Check for self-reference
-		ptr := uintptr(unsafe.Pointer(v))
-		if visited[ptr] {
-			return SelfRefStr
-		}
-		visited[ptr] = true
-		defer delete(visited, ptr)
-
+		// Instances print their class name, fields are not listed
 		classInstance := v.Obj.(*AtomClassInstance)
-
-		if (reflect.TypeOf(classInstance.Property.Obj) != reflect.TypeOf(&AtomValue{})) {
-			// For builtin class, obj might not be an AtomObject
-			builder := StringBuilderPool.Get().(*strings.Builder)
-			builder.Reset()
-			builder.WriteString(classInstance.Prototype.Obj.(*AtomClass).Name)
-			builder.WriteString(EmptyClassInstanceStr)
-			return builder.String()
-		}
-
-		prototype := classInstance.Prototype.Obj.(*AtomClass)
-		properties := classInstance.Property.Obj.(*AtomObject).Elements
-
-		if len(properties) == 0 {
-			// Fast path for empty class instance
-			builder := StringBuilderPool.Get().(*strings.Builder)
-			builder.Reset()
-			builder.WriteString(prototype.Name)
-			builder.WriteString(EmptyClassInstanceStr)
-			result := builder.String()
-			StringBuilderPool.Put(builder)
-			return result
-		}
-
-		// Use string builder for complex formatting
 		builder := StringBuilderPool.Get().(*strings.Builder)
 		builder.Reset()
-		builder.WriteString(prototype.Name)
-		builder.WriteString(" {\n")
-
-		// Process properties with optimized string building
-		first := true
-		for key, value := range properties {
-			if !first {
-				builder.WriteByte('\n')
-			}
-			first = false
-
-			builder.WriteString("  ")
-			builder.WriteString(key)
-			builder.WriteString(": ")
-			builder.WriteString(ValueToStringWithVisited(value, visited))
-			builder.WriteByte(',')
-		}
-		builder.WriteString("\n}")
+		builder.WriteString(classInstance.Prototype.Obj.(*AtomClass).Name)
+		builder.WriteString(EmptyClassInstanceStr)
 		result := builder.String()
 		StringBuilderPool.Put(builder)
 		return result
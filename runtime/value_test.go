@@ -0,0 +1,86 @@
+package runtime
+
+import "testing"
+
+// sink keeps the benchmarked values from being optimized away
+var sink *AtomValue
+
+func BenchmarkNewAtomValue(b *testing.B) {
+	b.Run("small int", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := range b.N {
+			sink = NewAtomValueInt(i % smallIntMax)
+		}
+	})
+	b.Run("large int", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := range b.N {
+			sink = NewAtomValueInt(smallIntMax + 1 + i%1024)
+		}
+	})
+	b.Run("num", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := range b.N {
+			sink = NewAtomValueNum(float64(i) + 0.5)
+		}
+	})
+}
+
+func BenchmarkDoAddition(b *testing.B) {
+	code := NewAtomCode("<benchmark>", "<benchmark>", false, 0)
+	frame := NewAtomCallFrame(nil, NewAtomGenericValue(AtomTypeFunc, code), 0)
+	one := NewAtomValueInt(1)
+
+	b.Run("small int", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := range b.N {
+			DoAddition(frame, NewAtomValueInt(i%smallIntMax), one)
+			frame.Stack.Pop()
+		}
+	})
+	b.Run("num", func(b *testing.B) {
+		half := NewAtomValueNum(0.5)
+		b.ReportAllocs()
+		for range b.N {
+			DoAddition(frame, half, one)
+			frame.Stack.Pop()
+		}
+	})
+}
+
+func BenchmarkDoCmpLt(b *testing.B) {
+	interpreter := NewInterpreter(NewAtomState())
+	code := NewAtomCode("<benchmark>", "<benchmark>", false, 0)
+	frame := NewAtomCallFrame(nil, NewAtomGenericValue(AtomTypeFunc, code), 0)
+	one := NewAtomValueInt(1)
+
+	b.ReportAllocs()
+	for i := range b.N {
+		DoCmpLt(interpreter, frame, NewAtomValueInt(i%smallIntMax), one)
+		frame.Stack.Pop()
+	}
+}
+
+func TestSmallIntsAreShared(t *testing.T) {
+	if NewAtomValueInt(smallIntMin) != NewAtomValueInt(smallIntMin) || NewAtomValueInt(smallIntMax) != NewAtomValueInt(smallIntMax) {
+		t.Fatal("small integers should be preallocated")
+	}
+	if NewAtomValueInt(smallIntMax+1) == NewAtomValueInt(smallIntMax+1) {
+		t.Fatal("integers past smallIntMax should be allocated")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { NewAtomValueInt(42) }); allocs != 0 {
+		t.Fatalf("small integer allocated %v times", allocs)
+	}
+}
+
+func TestBoolsAndNullAreShared(t *testing.T) {
+	if NewAtomValueTrue() != NewAtomValueTrue() || NewAtomValueFalse() != NewAtomValueFalse() || NewAtomValueNull() != NewAtomValueNull() {
+		t.Fatal("booleans and null should be preallocated")
+	}
+	if !CoerceToBool(NewAtomValueTrue()) || CoerceToBool(NewAtomValueFalse()) || !CheckType(NewAtomValueNull(), AtomTypeNull) {
+		t.Fatal("preallocated values have the wrong contents")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { NewAtomValueTrue() }); allocs != 0 {
+		t.Fatalf("true allocated %v times", allocs)
+	}
+}
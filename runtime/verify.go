@@ -0,0 +1,261 @@
+package runtime
+
+import "fmt"
+
+// verifier checks one AtomCode, see Verify.
+type verifier struct {
+	code      *AtomCode
+	functions int
+	starts    map[int]bool // Addresses of the instructions
+	depths    map[int]int  // Stack depth before the instruction at an address
+}
+
+// Verify checks that code is safe to execute before it runs, it is
+// meant for bytecode that was not produced by the compiler. Every opcode
+// must be known with its operands inside the code, constant, cache, slot
+// and capture indices must be in range and jumps must land on an
+// instruction or the end of the code. Following every path from the
+// entry, the stack must hold the same number of values wherever paths
+// meet, never underflow and hold exactly the returned value at OpReturn,
+// which every path must end with.
+// functions is the size of the function table OpLoadFunction reads.
+func Verify(code *AtomCode, functions int) error {
+	v := &verifier{
+		code:      code,
+		functions: functions,
+		starts:    map[int]bool{},
+		depths:    map[int]int{},
+	}
+	if err := v.decode(); err != nil {
+		return err
+	}
+	return v.flow()
+}
+
+func (v *verifier) fail(pc int, format string, args ...any) error {
+	return NewAtomVerifyError(v.code.Name, v.code.File, pc, fmt.Sprintf(format, args...))
+}
+
+// operand reads the operand at offset of the instruction at pc.
+func (v *verifier) operand(pc, offset int) int {
+	return ReadInt(v.code.Code, pc+1+offset)
+}
+
+// decode checks the instructions one after another.
+func (v *verifier) decode() error {
+	code := v.code.Code
+	for pc := 0; pc < len(code); pc += 1 + OperandSize(code[pc]) {
+		op := code[pc]
+		if op < OpMakeModule || op > OpReturn {
+			return v.fail(pc, "unknown opcode %d", op)
+		}
+		if pc+1+OperandSize(op) > len(code) {
+			return v.fail(pc, "%s needs %d bytes of operands, %d left", OpName(op), OperandSize(op), len(code)-pc-1)
+		}
+		v.starts[pc] = true
+		if err := v.operands(pc); err != nil {
+			return err
+		}
+	}
+	for pc := range v.starts {
+		if op := code[pc]; IsJump(op) {
+			target := v.operand(pc, 0)
+			if target != len(code) && !v.starts[target] {
+				return v.fail(pc, "%s jumps to %d, not an instruction", OpName(op), target)
+			}
+		}
+	}
+	return nil
+}
+
+// operands checks the indices the instruction at pc refers to.
+func (v *verifier) operands(pc int) error {
+	code := v.code
+	op := code.Code[pc]
+
+	constant := func(offset int, valueType AtomType) error {
+		index := v.operand(pc, offset)
+		if index >= len(code.Constants) {
+			return v.fail(pc, "%s reads constant %d of %d", OpName(op), index, len(code.Constants))
+		}
+		if code.Constants[index].Type != valueType {
+			return v.fail(pc, "%s expects a %s constant, constant %d is a %s", OpName(op), typeName(valueType), index, GetTypeString(code.Constants[index]))
+		}
+		return nil
+	}
+	inRange := func(offset int, size int, what string) error {
+		if index := v.operand(pc, offset); index >= size {
+			return v.fail(pc, "%s reads %s %d of %d", OpName(op), what, index, size)
+		}
+		return nil
+	}
+
+	switch op {
+	case OpLoadNum:
+		return constant(0, AtomTypeNum)
+	case OpLoadBigInt:
+		return constant(0, AtomTypeBigInt)
+	case OpLoadStr, OpLoadName, OpLoadModule, OpImportModule, OpPluckAttribute,
+		OpStoreModule, OpInitName, OpStoreName:
+		return constant(0, AtomTypeStr)
+	case OpMakeClass:
+		return constant(4, AtomTypeStr)
+	case OpLoadAttribute, OpStoreAttribute, OpCallAttribute:
+		if err := constant(0, AtomTypeStr); err != nil {
+			return err
+		}
+		return inRange(4, len(code.Caches), "cache")
+	case OpLoadLocal, OpInitLocal, OpStoreLocal, OpIncLocal, OpDecLocal,
+		OpLoadCell, OpInitCell, OpStoreCell:
+		return inRange(0, code.Locals, "slot")
+	case OpLoadCapture, OpStoreCapture:
+		return inRange(0, len(code.Captures), "capture")
+	case OpLoadFunction:
+		return inRange(0, v.functions, "function")
+	}
+	return nil
+}
+
+// effect returns how many values the instruction at pc pops and
+// pushes, when it does not jump.
+func (v *verifier) effect(pc int) (pops, pushes int) {
+	op := v.code.Code[pc]
+	switch op {
+	case OpMakeModule, OpLoadObject, OpMakeClass, OpMakeEnum:
+		return 2 * v.operand(pc, 0), 1
+	case OpLoadArray:
+		return v.operand(pc, 0), 1
+	case OpLoadInt, OpLoadNum, OpLoadBigInt, OpLoadStr, OpLoadBool, OpLoadNull,
+		OpLoadName, OpLoadLocal, OpLoadCell, OpLoadCapture, OpLoadModule, OpLoadFunction:
+		return 0, 1
+	case OpLoadBase, OpImportModule, OpAwait, OpLoadAttribute,
+		OpBitNot, OpNot, OpNeg, OpPos, OpInc, OpDec, OpTypeof:
+		return 1, 1
+	case OpExtendClass, OpIndex,
+		OpMul, OpDiv, OpMod, OpAdd, OpSub, OpShl, OpShr,
+		OpCmpLt, OpCmpLte, OpCmpGt, OpCmpGte, OpCmpEq, OpCmpNe,
+		OpAnd, OpOr, OpXor:
+		return 2, 1
+	case OpCallConstructor, OpCall, OpTailCall:
+		return v.operand(pc, 0) + 1, 1
+	case OpCallAttribute:
+		return v.operand(pc, 8) + 1, 1
+	case OpPluckAttribute, OpDupTop:
+		return 1, 2
+	case OpStoreModule, OpInitName, OpStoreName, OpInitLocal, OpStoreLocal,
+		OpInitCell, OpStoreCell, OpStoreCapture, OpPopTop, OpPopJumpIfFalse, OpPopJumpIfTrue:
+		return 1, 0
+	case OpStoreAttribute:
+		return 2, 0
+	case OpSetIndex:
+		return 3, 0
+	case OpJumpIfFalseOrPop, OpJumpIfTrueOrPop:
+		return 1, 0
+	case OpPeekJumpIfEqual:
+		return 2, 1
+	case OpPopJumpIfNotError:
+		return 1, 1
+	case OpCmpLtJumpIfFalse, OpCmpLteJumpIfFalse, OpCmpGtJumpIfFalse,
+		OpCmpGteJumpIfFalse, OpCmpEqJumpIfFalse, OpCmpNeJumpIfFalse:
+		return 2, 0
+	case OpDupTop2:
+		return 2, 4
+	case OpRot2:
+		return 2, 2
+	case OpRot3:
+		return 3, 3
+	case OpRot4:
+		return 4, 4
+	case OpReturn:
+		return 1, 0
+	}
+	return 0, 0
+}
+
+// flow follows every path from the entry, where the arguments of the
+// call are on the stack, and records the stack depth of each instruction.
+func (v *verifier) flow() error {
+	code := v.code.Code
+	if len(code) == 0 {
+		return v.fail(0, "empty code, expected %s", OpName(OpReturn))
+	}
+	pending := []int{0}
+	v.depths[0] = v.code.Argc
+
+	// reach records the depth a path arrives at pc with, paths
+	// leaving the code without OpReturn have no value to return
+	reach := func(from, pc, depth int) error {
+		if pc >= len(code) {
+			return v.fail(from, "%s runs off the end of the code without %s", OpName(code[from]), OpName(OpReturn))
+		}
+		if known, ok := v.depths[pc]; ok {
+			if known != depth {
+				return v.fail(pc, "stack depth %d from %d, %d from an earlier path", depth, from, known)
+			}
+			return nil
+		}
+		v.depths[pc] = depth
+		pending = append(pending, pc)
+		return nil
+	}
+
+	for len(pending) > 0 {
+		pc := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		op := code[pc]
+		depth := v.depths[pc]
+		pops, pushes := v.effect(pc)
+		if pops > depth {
+			return v.fail(pc, "%s pops %d values, the stack has %d", OpName(op), pops, depth)
+		}
+		next := pc + 1 + OperandSize(op)
+
+		switch {
+		case op == OpReturn:
+			if depth != 1 {
+				return v.fail(pc, "%s with %d values on the stack, expected 1", OpName(op), depth)
+			}
+
+		case op == OpJump || op == OpAbsoluteJump:
+			if err := reach(pc, v.operand(pc, 0), depth); err != nil {
+				return err
+			}
+
+		case op == OpJumpIfFalseOrPop || op == OpJumpIfTrueOrPop:
+			// The value stays on the stack when jumping
+			if err := reach(pc, v.operand(pc, 0), depth); err != nil {
+				return err
+			}
+			if err := reach(pc, next, depth-pops+pushes); err != nil {
+				return err
+			}
+
+		case IsJump(op):
+			if err := reach(pc, v.operand(pc, 0), depth-pops+pushes); err != nil {
+				return err
+			}
+			if err := reach(pc, next, depth-pops+pushes); err != nil {
+				return err
+			}
+
+		default:
+			if err := reach(pc, next, depth-pops+pushes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// typeName names the types of constants in errors.
+func typeName(valueType AtomType) string {
+	switch valueType {
+	case AtomTypeNum:
+		return "number"
+	case AtomTypeBigInt:
+		return "bigint"
+	default:
+		return "string"
+	}
+}